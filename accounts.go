@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// defaultAccount is used when no account is named. Credentials saved
+// before accounts existed belong to it.
+const defaultAccount = "default"
+
+// profileEnv names the account to use, like --profile.
+const profileEnv = "BLOCKBLOX_PROFILE"
+
+// configEnv names the directory for accounts and saved credentials, like
+// --config.
+const configEnv = "BLOCKBLOX_CONFIG"
+
+// configDir holds accounts.json and saved credentials. It is the value of
+// --config; when that is empty, BLOCKBLOX_CONFIG, else ~/.blockblox.
+type configDir string
+
+// custom reports whether --config or BLOCKBLOX_CONFIG moved the config
+// directory away from ~/.blockblox.
+func (d configDir) custom() bool {
+	return d != "" || os.Getenv(configEnv) != ""
+}
+
+// path returns the directory d stands for.
+func (d configDir) path() (string, error) {
+	if d != "" {
+		return string(d), nil
+	}
+	if dir := os.Getenv(configEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".blockblox"), nil
+}
+
+var accountNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// validateAccountName keeps account names usable as directory names and
+// keyring attributes.
+func validateAccountName(name string) error {
+	if !accountNameRe.MatchString(name) {
+		return usageErrorf("invalid account name %q (use up to 32 lowercase letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+// accountConfig is ~/.blockblox/accounts.json: which account is the
+// default, and what is known about each.
+type accountConfig struct {
+	Default  string                  `json:"default,omitempty"`
+	Accounts map[string]*accountInfo `json:"accounts"`
+
+	dir configDir // where it was loaded from and is saved to
+}
+
+// accountInfo is one account's settings, where its credentials came from
+// and the Roblox user they last signed in as.
+type accountInfo struct {
+	Store          string `json:"store,omitempty"`          // credential store, as for --store
+	Browser        string `json:"browser,omitempty"`        // init --browser they were extracted from
+	BrowserProfile string `json:"browserProfile,omitempty"` // and its profile directory
+	UserID         int64  `json:"userId,omitempty"`
+	Username       string `json:"username,omitempty"`
+	DisplayName    string `json:"displayName,omitempty"`
+}
+
+func accountsPath(cfg configDir) (string, error) {
+	dir, err := cfg.path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "accounts.json"), nil
+}
+
+// loadAccounts reads the account config. A missing file is an empty one.
+func loadAccounts(cfg configDir) (*accountConfig, error) {
+	config := &accountConfig{Accounts: map[string]*accountInfo{}, dir: cfg}
+	path, err := accountsPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if config.Accounts == nil {
+		config.Accounts = map[string]*accountInfo{}
+	}
+	return config, nil
+}
+
+func (c *accountConfig) save() error {
+	path, err := accountsPath(c.dir)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0600)
+}
+
+// defaultName returns the account used when none is named.
+func (c *accountConfig) defaultName() string {
+	if c.Default != "" {
+		return c.Default
+	}
+	return defaultAccount
+}
+
+// info returns name's entry, adding an empty one if it has none.
+func (c *accountConfig) info(name string) *accountInfo {
+	if c.Accounts[name] == nil {
+		c.Accounts[name] = &accountInfo{}
+	}
+	return c.Accounts[name]
+}
+
+// accountDir holds an account's saved credentials. The default account
+// keeps the locations used before accounts existed.
+func accountDir(cfg configDir, name string) (string, error) {
+	dir, err := cfg.path()
+	if err != nil {
+		return "", err
+	}
+	if name == defaultAccount {
+		return dir, nil
+	}
+	return filepath.Join(dir, "profiles", name), nil
+}
+
+// accountCacheDir holds an account's CSRF token.
+func accountCacheDir(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	if name == defaultAccount {
+		return filepath.Join(dir, "blockblox"), nil
+	}
+	return filepath.Join(dir, "blockblox", "profiles", name), nil
+}
+
+// selectAccount returns the account to use: the one given with --profile,
+// else BLOCKBLOX_PROFILE, else the default. named reports whether it was
+// chosen explicitly.
+func selectAccount(cfg configDir, flagValue string) (name string, named bool, err error) {
+	name = flagValue
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name != "" {
+		return name, true, validateAccountName(name)
+	}
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return "", false, err
+	}
+	return config.defaultName(), false, nil
+}
+
+// rememberUser records who name's credentials sign in as, for accounts
+// list. It is best effort: failing to write it never fails a command.
+func rememberUser(cfg configDir, name string, user *roblox.UserResponse) {
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return
+	}
+	info := config.info(name)
+	if info.UserID == user.ID && info.Username == user.Name && info.DisplayName == user.DisplayName {
+		return
+	}
+	info.UserID, info.Username, info.DisplayName = user.ID, user.Name, user.DisplayName
+	config.save()
+}
+
+// rememberBrowser records the browser profile account's credentials were
+// extracted from, or clears it when both are "" because they were given by
+// hand, so an expired session can be renewed from the same place.
+func rememberBrowser(cfg configDir, account, browser, profile string) error {
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return err
+	}
+	info := config.info(account)
+	if info.Browser == browser && info.BrowserProfile == profile {
+		return nil
+	}
+	info.Browser, info.BrowserProfile = browser, profile
+	return config.save()
+}
+
+// accountExists reports whether name has saved credentials or settings.
+func accountExists(config *accountConfig, name string, stderr io.Writer) bool {
+	if _, ok := config.Accounts[name]; ok {
+		return true
+	}
+	_, err := findCredentialStore(config.dir, name, stderr)
+	return err == nil
+}
+
+func printAccountsUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  blockblox accounts list                      List accounts and who they sign in as")
+	fmt.Fprintln(w, "  blockblox accounts add <name> [init flags]   Extract credentials for a new account")
+	fmt.Fprintln(w, "  blockblox accounts remove <name>             Delete an account and its credentials")
+	fmt.Fprintln(w, "  blockblox accounts rename <old> <new>        Rename an account")
+	fmt.Fprintln(w, "  blockblox accounts default <name>            Use an account when --profile is not given")
+}
+
+// runAccounts manages named accounts. accounts add is handled by
+// addAccount, as it continues into init and get.
+func runAccounts(w, stderr io.Writer, cfg configDir, args []string) error {
+	if len(args) < 1 {
+		printAccountsUsage(stderr)
+		return usageErrorf("missing accounts subcommand")
+	}
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return listAccounts(w, stderr, config)
+	case "remove":
+		if len(args) < 2 {
+			return usageErrorf("missing account name\nUsage: blockblox accounts remove <name>")
+		}
+		return removeAccount(w, stderr, config, args[1])
+	case "rename":
+		if len(args) < 3 {
+			return usageErrorf("missing account name\nUsage: blockblox accounts rename <old> <new>")
+		}
+		return renameAccount(w, stderr, config, args[1], args[2])
+	case "default":
+		if len(args) < 2 {
+			return usageErrorf("missing account name\nUsage: blockblox accounts default <name>")
+		}
+		name := args[1]
+		if !accountExists(config, name, stderr) {
+			return usageErrorf("unknown account %q (run 'blockblox accounts list')", name)
+		}
+		config.Default = name
+		if name == defaultAccount {
+			config.Default = ""
+		}
+		if err := config.save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Default account is now %s\n", name)
+		return nil
+	default:
+		printAccountsUsage(stderr)
+		return usageErrorf("unknown accounts subcommand: %s", args[0])
+	}
+}
+
+// addAccount is init for an account that does not exist yet, taking its
+// name and then init's flags.
+func (c *cli) addAccount(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(c.stderr, "Error: missing account name")
+		fmt.Fprintln(c.stderr, "Usage: blockblox accounts add <name> [--browser <browser>] [--profile <profile>] [--store <store>]")
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("blockblox accounts add", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	opts := defineInitFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsage
+	}
+	if err := prepareAddAccount(c.stderr, c.config, args[0]); err != nil {
+		return c.failed(err)
+	}
+	c.account = args[0]
+	return c.initAccount(opts)
+}
+
+// prepareAddAccount checks that name is free for accounts add and records
+// it, making it the default when it is the first account.
+func prepareAddAccount(stderr io.Writer, cfg configDir, name string) error {
+	if err := validateAccountName(name); err != nil {
+		return err
+	}
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return err
+	}
+	if accountExists(config, name, stderr) {
+		return fmt.Errorf("account %q already exists (run 'blockblox --profile %s init' to refresh its credentials)", name, name)
+	}
+	if config.Default == "" && !accountExists(config, defaultAccount, stderr) {
+		config.Default = name
+	}
+	config.info(name)
+	return config.save()
+}
+
+func listAccounts(w, stderr io.Writer, config *accountConfig) error {
+	names := []string{}
+	for name := range config.Accounts {
+		names = append(names, name)
+	}
+	if _, ok := config.Accounts[defaultAccount]; !ok && accountExists(config, defaultAccount, stderr) {
+		names = append(names, defaultAccount)
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(w, "No accounts yet. Run 'blockblox init' or 'blockblox accounts add <name>'.")
+		return nil
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tACCOUNT\tUSER\tCREDENTIALS")
+	for _, name := range names {
+		marker := ""
+		if name == config.defaultName() {
+			marker = "*"
+		}
+		user := "-"
+		if info := config.Accounts[name]; info != nil && info.UserID != 0 {
+			user = fmt.Sprintf("%s (@%s)", info.DisplayName, info.Username)
+		}
+		where := "none"
+		if store, err := findCredentialStore(config.dir, name, stderr); err == nil {
+			where = store.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", marker, name, user, where)
+	}
+	return tw.Flush()
+}
+
+func removeAccount(w, stderr io.Writer, config *accountConfig, name string) error {
+	if !accountExists(config, name, stderr) {
+		return usageErrorf("unknown account %q (run 'blockblox accounts list')", name)
+	}
+	for _, store := range credentialStores(config.dir, name, stderr) {
+		if store.Exists() {
+			if err := store.Delete(); err != nil {
+				return err
+			}
+		}
+	}
+	if name != defaultAccount {
+		if dir, err := accountDir(config.dir, name); err == nil {
+			os.RemoveAll(dir)
+		}
+	}
+	if dir, err := accountCacheDir(name); err == nil {
+		os.Remove(filepath.Join(dir, "csrf-token"))
+	}
+
+	delete(config.Accounts, name)
+	if config.Default == name {
+		config.Default = ""
+	}
+	if err := config.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Removed account %s\n", name)
+	return nil
+}
+
+func renameAccount(w, stderr io.Writer, config *accountConfig, oldName, newName string) error {
+	if !accountExists(config, oldName, stderr) {
+		return usageErrorf("unknown account %q (run 'blockblox accounts list')", oldName)
+	}
+	if err := validateAccountName(newName); err != nil {
+		return err
+	}
+	if accountExists(config, newName, stderr) {
+		return fmt.Errorf("account %q already exists", newName)
+	}
+
+	if source, err := findCredentialStore(config.dir, oldName, stderr); err == nil {
+		target, err := newCredentialStore(config.dir, newName, storeName(source), stderr)
+		if err != nil {
+			return err
+		}
+		if err := moveCredentials(source, target); err != nil {
+			return err
+		}
+	}
+	if dir, err := accountCacheDir(oldName); err == nil {
+		os.Remove(filepath.Join(dir, "csrf-token"))
+	}
+
+	config.Accounts[newName] = config.info(oldName)
+	delete(config.Accounts, oldName)
+	if config.defaultName() == oldName {
+		config.Default = newName
+	}
+	if config.Default == defaultAccount {
+		config.Default = ""
+	}
+	if err := config.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Renamed account %s to %s\n", oldName, newName)
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/astrostl/blockblox/fakeroblox"
+)
+
+// TestAccountsLifecycle runs accounts commands one after another against the
+// same $HOME, checking what each leaves behind for the next.
+func TestAccountsLifecycle(t *testing.T) {
+	server := fakeroblox.New(fakeroblox.WithClock(fakeroblox.FixedClock(testNow)))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("BLOCKBLOX_API_URL", ts.URL)
+	t.Setenv("ROBLOX_SECURITY", "")
+	t.Setenv("ROBLOX_BROWSER_TRACKER", "")
+	t.Setenv(passphraseEnv, "hunter2")
+	saveTestCredentials(t, "alex", storeFile, server.Credentials(), "hunter2")
+
+	blockblox := func(wantCode int, args ...string) string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := run(context.Background(), args, &stdout, &stderr); code != wantCode {
+			t.Fatalf("blockblox %s: exit status %d, want %d\nstderr: %s", strings.Join(args, " "), code, wantCode, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	// Using an account remembers who it signs in as.
+	blockblox(0, "--profile", "alex", "get")
+	if out := blockblox(0, "accounts", "list"); !strings.Contains(out, "Alex (@CoolPlayer123)") {
+		t.Errorf("accounts list does not show the remembered user:\n%s", out)
+	}
+
+	blockblox(0, "accounts", "default", "alex")
+	blockblox(0, "get")
+
+	// Renaming keeps the credentials, their passphrase and the default.
+	blockblox(0, "accounts", "rename", "alex", "sam")
+	blockblox(0, "--profile", "sam", "get")
+	blockblox(exitAuth, "--profile", "alex", "get")
+	blockblox(0, "get")
+
+	blockblox(0, "accounts", "remove", "sam")
+	blockblox(exitAuth, "--profile", "sam", "get")
+	if out := blockblox(0, "accounts", "list"); !strings.Contains(out, "No accounts yet") {
+		t.Errorf("accounts list after removing the only account:\n%s", out)
+	}
+}
+
+// TestConcurrentConfigDirs runs blockblox with different --config
+// directories at once: each run must only see its own accounts.
+func TestConcurrentConfigDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv(configEnv, "")
+
+	names := []string{"alex", "sam", "jo", "kim"}
+	dirs := make([]string, len(names))
+	for i, name := range names {
+		dirs[i] = filepath.Join(home, name)
+		data := fmt.Sprintf(`{"default":%q,"accounts":{%q:{}}}`, name, name)
+		if err := os.MkdirAll(dirs[i], 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dirs[i], "accounts.json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				var stdout, stderr bytes.Buffer
+				if code := run(context.Background(), []string{"--config", dirs[i], "accounts", "list"}, &stdout, &stderr); code != 0 {
+					t.Errorf("accounts list in %s: exit status %d\nstderr: %s", dirs[i], code, stderr.String())
+					return
+				}
+				for _, other := range names {
+					if out := stdout.String(); strings.Contains(out, other) != (other == name) {
+						t.Errorf("accounts list in %s:\n%s", dirs[i], out)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
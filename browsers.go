@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// browserIDs returns the init --browser values supported on this
+// platform, in search order.
+func browserIDs() []string {
+	browsers, _ := chromiumBrowsers()
+	var ids []string
+	for _, b := range browsers {
+		ids = append(ids, b.id)
+	}
+	return append(ids, "firefox")
+}
+
+// browserChoices lists browserIDs for messages.
+func browserChoices() string {
+	return strings.Join(browserIDs(), ", ")
+}
+
+// cookieProfile is a browser profile that is logged into Roblox.
+type cookieProfile struct {
+	id      string // init --browser value, e.g. "brave"
+	browser string // e.g. "Brave"
+	dir     string // profile directory, e.g. "Profile 2"
+	name    string // display name, e.g. "Work"; may be empty
+	read    func() (security string, browserTracker string, err error)
+}
+
+func (p cookieProfile) String() string {
+	if p.name != "" && p.name != p.dir {
+		return fmt.Sprintf("%s - %s (%s)", p.browser, p.name, p.dir)
+	}
+	return fmt.Sprintf("%s - %s", p.browser, p.dir)
+}
+
+// matches reports whether p is the profile named on the command line,
+// by directory or display name.
+func (p cookieProfile) matches(profile string) bool {
+	return strings.EqualFold(p.dir, profile) || (p.name != "" && strings.EqualFold(p.name, profile))
+}
+
+// robloxProfiles returns the profiles logged into Roblox, Chrome's Default
+// profile first. browser limits the search to one browser; "" searches all
+// of them.
+func robloxProfiles(browser string) ([]cookieProfile, error) {
+	var found []cookieProfile
+
+	browsers, err := chromiumBrowsers()
+	if err != nil && browser != "" && browser != "firefox" {
+		return nil, err
+	}
+	for _, b := range browsers {
+		if browser != "" && browser != b.id {
+			continue
+		}
+		profiles := chromiumProfiles(b)
+		if len(profiles) == 0 && browser != "" {
+			return nil, fmt.Errorf("%s profiles not found in %s", b.name, b.userDataDir)
+		}
+		for _, p := range profiles {
+			if ok, err := hasChromeRobloxCookie(p); err != nil || !ok {
+				continue
+			}
+			found = append(found, cookieProfile{
+				id:      b.id,
+				browser: b.name,
+				dir:     p.dir,
+				name:    p.name,
+				read:    func() (string, string, error) { return readChromeCookies(p) },
+			})
+		}
+	}
+
+	if browser == "" || browser == "firefox" {
+		profiles, err := findFirefoxProfiles()
+		if err != nil && browser == "firefox" {
+			return nil, err
+		}
+		for _, p := range profiles {
+			if security, _, err := firefoxCookies(p); err != nil || security == "" {
+				continue
+			}
+			found = append(found, cookieProfile{
+				id:      "firefox",
+				browser: "Firefox",
+				dir:     filepath.Base(p.path),
+				name:    p.name,
+				read:    func() (string, string, error) { return readFirefoxCookies(p) },
+			})
+		}
+	}
+
+	return found, nil
+}
+
+// browserName returns the display name for an init --browser value, or a
+// generic one for "", which searches every browser. It reports false for
+// browsers blockblox doesn't know.
+func browserName(browser string) (string, bool) {
+	switch browser {
+	case "":
+		return "your browsers", true
+	case "firefox":
+		return "Firefox", true
+	}
+	browsers, _ := chromiumBrowsers()
+	for _, b := range browsers {
+		if b.id == browser {
+			return b.name, true
+		}
+	}
+	return "", false
+}
+
+// chooseProfile asks which of several logged-in profiles to use.
+func chooseProfile(w io.Writer, in io.Reader, profiles []cookieProfile) (cookieProfile, error) {
+	fmt.Fprintln(w, "Several browser profiles are logged into Roblox:")
+	for i, p := range profiles {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, p)
+	}
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(w, "Choose a profile [1-%d]: ", len(profiles))
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return cookieProfile{}, errors.New("no profile chosen")
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err == nil && n >= 1 && n <= len(profiles) {
+			return profiles[n-1], nil
+		}
+		fmt.Fprintln(w, "Invalid choice")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Chrome-family cookie extraction. Where each browser keeps its profiles and
+// how its key is obtained differ per platform; see chrome_darwin.go and
+// chrome_linux.go.
+
+// safeStoragePasswordEnv supplies the browser's safe storage password
+// directly, skipping the Keychain or keyring lookup.
+const safeStoragePasswordEnv = "BLOCKBLOX_SAFE_STORAGE_PASSWORD"
+
+// chromiumBrowser is one Chrome-family browser installation.
+type chromiumBrowser struct {
+	id          string // init --browser value, e.g. "brave"
+	name        string // shown to the user, e.g. "Brave"
+	keyring     string // name in its "<keyring> Safe Storage" password entry
+	userDataDir string // holds "Local State" and one directory per profile
+}
+
+// safeStorageName is the Keychain service and keyring entry holding the
+// browser's cookie password.
+func (b chromiumBrowser) safeStorageName() string {
+	return b.keyring + " Safe Storage"
+}
+
+// chromiumProfile is one profile directory of a Chrome-family browser.
+type chromiumProfile struct {
+	browser chromiumBrowser
+	dir     string // e.g. "Default" or "Profile 2"
+	name    string // display name from Local State, e.g. "Work"
+}
+
+// cookiesPath returns the profile's cookie database. Chrome 96 moved it
+// into a Network subdirectory; older profiles keep it at the top level.
+func (p chromiumProfile) cookiesPath() string {
+	dir := filepath.Join(p.browser.userDataDir, p.dir)
+	if path := filepath.Join(dir, "Network", "Cookies"); fileExists(path) {
+		return path
+	}
+	return filepath.Join(dir, "Cookies")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// chromiumProfiles lists the browser's profiles with a cookie database,
+// Default first. Display names come from Local State when it is readable.
+func chromiumProfiles(b chromiumBrowser) []chromiumProfile {
+	var localState struct {
+		Profile struct {
+			InfoCache map[string]struct {
+				Name string `json:"name"`
+			} `json:"info_cache"`
+		} `json:"profile"`
+	}
+	if data, err := os.ReadFile(filepath.Join(b.userDataDir, "Local State")); err == nil {
+		json.Unmarshal(data, &localState)
+	}
+
+	dirs := map[string]bool{}
+	for dir := range localState.Profile.InfoCache {
+		dirs[dir] = true
+	}
+	entries, _ := os.ReadDir(b.userDataDir)
+	for _, e := range entries {
+		if e.IsDir() && (e.Name() == "Default" || strings.HasPrefix(e.Name(), "Profile ")) {
+			dirs[e.Name()] = true
+		}
+	}
+
+	var profiles []chromiumProfile
+	for dir := range dirs {
+		p := chromiumProfile{browser: b, dir: dir, name: localState.Profile.InfoCache[dir].Name}
+		if fileExists(p.cookiesPath()) {
+			profiles = append(profiles, p)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if (profiles[i].dir == "Default") != (profiles[j].dir == "Default") {
+			return profiles[i].dir == "Default"
+		}
+		return profiles[i].dir < profiles[j].dir
+	})
+	return profiles
+}
+
+// safeStoragePassword returns the override from the environment, if set.
+func safeStoragePassword() (string, bool) {
+	return os.LookupEnv(safeStoragePasswordEnv)
+}
+
+// deriveChromeKey turns a safe storage password into the AES-128 key Chrome
+// encrypts cookies with.
+func deriveChromeKey(password string, iterations int) []byte {
+	return pbkdf2.Key([]byte(password), []byte("saltysalt"), iterations, 16, sha1.New)
+}
+
+// chromeHostDigestVersion is the first cookie database version whose
+// decrypted values begin with the SHA-256 digest of their host_key.
+const chromeHostDigestVersion = 24
+
+// errCookieKey means a cookie decrypted to garbage, almost always because
+// the safe storage password was wrong.
+var errCookieKey = errors.New("cookie did not decrypt (wrong safe storage password?)")
+
+// chromeCookieDBVersion reads the schema version from the meta table.
+func chromeCookieDBVersion(db *sql.DB) (int, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("reading cookie database version: %w", err)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cookie database version %q", value)
+	}
+	return version, nil
+}
+
+// decryptCookieValue decrypts one encrypted_value from a database at
+// dbVersion. key returns the AES key for a version prefix such as "v10", so
+// keys are only fetched for versions actually present.
+func decryptCookieValue(encryptedValue []byte, hostKey string, dbVersion int, key func(version string) ([]byte, error)) (string, error) {
+	if len(encryptedValue) < 3 {
+		return "", fmt.Errorf("encrypted value too short")
+	}
+
+	// Encrypted cookies are prefixed with "v10" or "v11"
+	version := string(encryptedValue[:3])
+	if version != "v10" && version != "v11" {
+		return "", fmt.Errorf("unsupported cookie encryption %q", version)
+	}
+
+	encryptedValue = encryptedValue[3:]
+
+	if len(encryptedValue) < aes.BlockSize || len(encryptedValue)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("encrypted value is %d bytes, not a whole number of AES blocks", len(encryptedValue))
+	}
+
+	k, err := key(version)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return "", err
+	}
+
+	// Chrome uses a fixed IV of 16 spaces
+	iv := []byte("                ") // 16 spaces
+
+	mode := cipher.NewCBCDecrypter(block, iv)
+
+	decrypted := make([]byte, len(encryptedValue))
+	mode.CryptBlocks(decrypted, encryptedValue)
+
+	decrypted, err = unpadPKCS7(decrypted)
+	if err != nil {
+		return "", err
+	}
+
+	// Newer databases bind each value to its host by prepending the
+	// host's digest.
+	if dbVersion >= chromeHostDigestVersion {
+		digest := sha256.Sum256([]byte(hostKey))
+		if len(decrypted) < len(digest) || !bytes.Equal(decrypted[:len(digest)], digest[:]) {
+			return "", fmt.Errorf("%w: host digest mismatch", errCookieKey)
+		}
+		decrypted = decrypted[len(digest):]
+	}
+
+	return string(decrypted), nil
+}
+
+// unpadPKCS7 removes PKCS #7 padding, checking every padding byte. A wrong
+// key leaves random bytes here, so this is where one is detected.
+func unpadPKCS7(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: empty plaintext", errCookieKey)
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, fmt.Errorf("%w: invalid padding", errCookieKey)
+	}
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, fmt.Errorf("%w: invalid padding", errCookieKey)
+		}
+	}
+	return b[:len(b)-n], nil
+}
+
+// hasChromeRobloxCookie reports whether the profile holds a .ROBLOSECURITY
+// cookie. Nothing is decrypted, so no key is needed.
+func hasChromeRobloxCookie(p chromiumProfile) (bool, error) {
+	tmpPath, cleanup, err := copyCookieDatabase(p.cookiesPath())
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	db, err := sql.Open("sqlite3", tmpPath)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	var n int
+	err = db.QueryRow(`
+		SELECT COUNT(*)
+		FROM cookies
+		WHERE host_key LIKE '%roblox.com'
+		AND name = '.ROBLOSECURITY'
+	`).Scan(&n)
+	return n > 0, err
+}
+
+func readChromeCookies(p chromiumProfile) (security string, browserTracker string, err error) {
+	// Copy cookies file to temp location (Chrome locks the original)
+	tmpPath, cleanup, err := copyCookieDatabase(p.cookiesPath())
+	if err != nil {
+		return "", "", err
+	}
+	defer cleanup()
+
+	// Keys are fetched on first use and remembered, so the Keychain or
+	// keyring is asked at most once per version.
+	keys := map[string][]byte{}
+	key := func(version string) ([]byte, error) {
+		if k, ok := keys[version]; ok {
+			return k, nil
+		}
+		k, err := getChromeEncryptionKey(p.browser, version)
+		if err != nil {
+			return nil, err
+		}
+		keys[version] = k
+		return k, nil
+	}
+
+	// Open the database
+	db, err := sql.Open("sqlite3", tmpPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer db.Close()
+
+	version, err := chromeCookieDBVersion(db)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Query for Roblox cookies. Values Chrome did not encrypt are kept in
+	// the value column instead.
+	rows, err := db.Query(`
+		SELECT host_key, name, value, encrypted_value
+		FROM cookies
+		WHERE host_key LIKE '%roblox.com'
+		AND name IN ('.ROBLOSECURITY', 'RBXEventTrackerV2')
+	`)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hostKey, name, value string
+		var encryptedValue []byte
+		if err := rows.Scan(&hostKey, &name, &value, &encryptedValue); err != nil {
+			return "", "", err
+		}
+
+		if len(encryptedValue) > 0 {
+			value, err = decryptCookieValue(encryptedValue, hostKey, version, key)
+			if err != nil {
+				return "", "", fmt.Errorf("could not decrypt %s cookie from %s: %w", name, p.browser.name, err)
+			}
+		}
+
+		switch name {
+		case ".ROBLOSECURITY":
+			security = value
+		case "RBXEventTrackerV2":
+			browserTracker = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+
+	if security == "" {
+		return "", "", fmt.Errorf(".ROBLOSECURITY cookie not found - make sure you're logged into Roblox in %s", p.browser.name)
+	}
+	if browserTracker == "" {
+		return "", "", fmt.Errorf("RBXEventTrackerV2 cookie not found")
+	}
+
+	return security, browserTracker, nil
+}
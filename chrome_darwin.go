@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Chrome-family cookie extraction for macOS
+
+func chromiumBrowsers() ([]chromiumBrowser, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	support := filepath.Join(home, "Library", "Application Support")
+	return []chromiumBrowser{
+		{id: "chrome", name: "Chrome", keyring: "Chrome", userDataDir: filepath.Join(support, "Google", "Chrome")},
+		{id: "chromium", name: "Chromium", keyring: "Chromium", userDataDir: filepath.Join(support, "Chromium")},
+		{id: "brave", name: "Brave", keyring: "Brave", userDataDir: filepath.Join(support, "BraveSoftware", "Brave-Browser")},
+		{id: "edge", name: "Edge", keyring: "Microsoft Edge", userDataDir: filepath.Join(support, "Microsoft Edge")},
+		{id: "vivaldi", name: "Vivaldi", keyring: "Vivaldi", userDataDir: filepath.Join(support, "Vivaldi")},
+		{id: "arc", name: "Arc", keyring: "Arc", userDataDir: filepath.Join(support, "Arc", "User Data")},
+	}, nil
+}
+
+// getChromeEncryptionKey derives the cookie key from the safe storage
+// password in the Keychain. macOS only ever writes v10 cookies.
+func getChromeEncryptionKey(browser chromiumBrowser, version string) ([]byte, error) {
+	if version != "v10" {
+		return nil, fmt.Errorf("unsupported cookie encryption %s", version)
+	}
+
+	password, ok := safeStoragePassword()
+	if !ok {
+		// Get the encryption key from macOS Keychain
+		cmd := exec.Command("security", "find-generic-password", "-s", browser.safeStorageName(), "-w")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s encryption key from Keychain: %w", browser.name, err)
+		}
+		password = strings.TrimSpace(string(output))
+	}
+
+	return deriveChromeKey(password, 1003), nil
+}
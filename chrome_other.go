@@ -0,0 +1,16 @@
+//go:build !darwin && !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func chromiumBrowsers() ([]chromiumBrowser, error) {
+	return nil, fmt.Errorf("Chrome cookie extraction is not supported on %s", runtime.GOOS)
+}
+
+func getChromeEncryptionKey(browser chromiumBrowser, version string) ([]byte, error) {
+	return nil, fmt.Errorf("Chrome cookie extraction is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fixtureCookie is one row of a generated Chrome cookie database. Rows with
+// a non-empty encrypted value leave value empty, as Chrome does.
+type fixtureCookie struct {
+	host, name, value string
+	encrypted         []byte
+}
+
+// writeChromeCookieDB creates a Chrome cookie database at path with the
+// given meta table version.
+func writeChromeCookieDB(t *testing.T, path string, version int, cookies []fixtureCookie) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE meta (key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)`,
+		`CREATE TABLE cookies (host_key TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, encrypted_value BLOB NOT NULL)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO meta (key, value) VALUES ('version', ?)`, version); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cookies {
+		value := c.value
+		if c.encrypted != nil {
+			value = ""
+		}
+		encrypted := c.encrypted
+		if encrypted == nil {
+			encrypted = []byte{}
+		}
+		if _, err := db.Exec(`INSERT INTO cookies (host_key, name, value, encrypted_value) VALUES (?, ?, ?, ?)`, c.host, c.name, value, encrypted); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// encryptCookie encrypts value the way Chrome does for a database at
+// dbVersion.
+func encryptCookie(t *testing.T, key []byte, prefix, host, value string, dbVersion int) []byte {
+	t.Helper()
+	plaintext := []byte(value)
+	if dbVersion >= chromeHostDigestVersion {
+		digest := sha256.Sum256([]byte(host))
+		plaintext = append(digest[:], plaintext...)
+	}
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(n)}, n)...)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, []byte("                ")).CryptBlocks(out, plaintext)
+	return append([]byte(prefix), out...)
+}
+
+// tempChromiumProfile returns an empty Default profile of browser in a
+// temporary directory.
+func tempChromiumProfile(t *testing.T, browser chromiumBrowser) chromiumProfile {
+	t.Helper()
+	browser.userDataDir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(browser.userDataDir, "Default"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return chromiumProfile{browser: browser, dir: "Default"}
+}
+
+func TestDecryptCookieValue(t *testing.T) {
+	key := deriveChromeKey("s3cret", 1)
+	wrongKey := deriveChromeKey("wrong", 1)
+	keyFor := func(k []byte) func(string) ([]byte, error) {
+		return func(string) ([]byte, error) { return k, nil }
+	}
+	const host = ".roblox.com"
+	const value = "_|WARNING:-DO-NOT-SHARE-THIS.--Sharing-this-will-allow-someone-to-log-in-as-you|_ABC"
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+		dbVersion int
+		key       []byte
+		want      string
+		wantErr   string
+		keyErr    bool
+	}{
+		{name: "v10 without host digest", encrypted: encryptCookie(t, key, "v10", host, value, 23), dbVersion: 23, key: key, want: value},
+		{name: "v11 without host digest", encrypted: encryptCookie(t, key, "v11", host, value, 23), dbVersion: 23, key: key, want: value},
+		{name: "host digest stripped", encrypted: encryptCookie(t, key, "v10", host, value, 24), dbVersion: 24, key: key, want: value},
+		{name: "tracker value kept intact", encrypted: encryptCookie(t, key, "v10", host, "CreateDate=1/1/2025&browserid=1", 23), dbVersion: 23, key: key, want: "CreateDate=1/1/2025&browserid=1"},
+		{name: "empty value", encrypted: encryptCookie(t, key, "v10", host, "", 24), dbVersion: 24, key: key, want: ""},
+		{name: "digest for another host", encrypted: encryptCookie(t, key, "v10", "www.roblox.com", value, 24), dbVersion: 24, key: key, keyErr: true},
+		{name: "digest expected but missing", encrypted: encryptCookie(t, key, "v10", host, "short", 23), dbVersion: 24, key: key, keyErr: true},
+		{name: "wrong key", encrypted: encryptCookie(t, key, "v11", host, value, 24), dbVersion: 24, key: wrongKey, keyErr: true},
+		{name: "unknown prefix", encrypted: append([]byte("v20"), make([]byte, 32)...), dbVersion: 24, key: key, wantErr: `unsupported cookie encryption "v20"`},
+		{name: "too short", encrypted: []byte("v1"), dbVersion: 24, key: key, wantErr: "too short"},
+		{name: "partial block", encrypted: append([]byte("v10"), make([]byte, 20)...), dbVersion: 24, key: key, wantErr: "not a whole number of AES blocks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptCookieValue(tt.encrypted, host, tt.dbVersion, keyFor(tt.key))
+			switch {
+			case tt.keyErr:
+				if !errors.Is(err, errCookieKey) {
+					t.Fatalf("err = %v, want errCookieKey", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tt.want:
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnpadPKCS7(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want []byte
+		ok   bool
+	}{
+		{in: append([]byte("abc"), bytes.Repeat([]byte{13}, 13)...), want: []byte("abc"), ok: true},
+		{in: bytes.Repeat([]byte{16}, 16), want: []byte{}, ok: true},
+		{in: []byte("abcdefghijklmno\x01"), want: []byte("abcdefghijklmno"), ok: true},
+		{in: []byte("abcdefghijklmn\x01\x02"), ok: false},
+		{in: []byte("abcdefghijklmno\x00"), ok: false},
+		{in: []byte("abcdefghijklmno\x11"), ok: false},
+		{in: []byte{}, ok: false},
+	}
+	for _, tt := range tests {
+		got, err := unpadPKCS7(tt.in)
+		if tt.ok != (err == nil) {
+			t.Errorf("unpadPKCS7(%q) err = %v, want ok = %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && !bytes.Equal(got, tt.want) {
+			t.Errorf("unpadPKCS7(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadChromeCookies(t *testing.T) {
+	const password = "fixture-password"
+	t.Setenv(safeStoragePasswordEnv, password)
+	browser := chromiumBrowser{id: "chrome", name: "Chrome", keyring: "Chrome"}
+
+	// Encrypt with whichever scheme this platform's Chrome uses for a
+	// password from the keyring.
+	prefix := "v11"
+	key, err := getChromeEncryptionKey(browser, prefix)
+	if err != nil {
+		prefix = "v10"
+		if key, err = getChromeEncryptionKey(browser, prefix); err != nil {
+			t.Skipf("Chrome cookie encryption unsupported here: %v", err)
+		}
+	}
+
+	const security = "_|WARNING:-DO-NOT-SHARE-THIS.|_SECRET"
+	const tracker = "CreateDate=1/1/2025 12:00:00 PM&rbxid=&browserid=42"
+
+	for _, version := range []int{18, 23, 24} {
+		t.Run("version "+strconv.Itoa(version), func(t *testing.T) {
+			profile := tempChromiumProfile(t, browser)
+			writeChromeCookieDB(t, profile.cookiesPath(), version, []fixtureCookie{
+				{host: ".roblox.com", name: ".ROBLOSECURITY", encrypted: encryptCookie(t, key, prefix, ".roblox.com", security, version)},
+				{host: ".roblox.com", name: "RBXEventTrackerV2", encrypted: encryptCookie(t, key, prefix, ".roblox.com", tracker, version)},
+				{host: ".example.com", name: "other", encrypted: encryptCookie(t, key, prefix, ".example.com", "x", version)},
+			})
+
+			gotSecurity, gotTracker, err := readChromeCookies(profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotSecurity != security || gotTracker != tracker {
+				t.Fatalf("got (%q, %q), want (%q, %q)", gotSecurity, gotTracker, security, tracker)
+			}
+		})
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		profile := tempChromiumProfile(t, browser)
+		writeChromeCookieDB(t, profile.cookiesPath(), 24, []fixtureCookie{
+			{host: ".roblox.com", name: ".ROBLOSECURITY", encrypted: encryptCookie(t, key, prefix, ".roblox.com", security, 24)},
+		})
+
+		t.Setenv(safeStoragePasswordEnv, "not-"+password)
+		_, _, err := readChromeCookies(profile)
+		if !errors.Is(err, errCookieKey) {
+			t.Fatalf("err = %v, want errCookieKey", err)
+		}
+	})
+
+	t.Run("missing meta version", func(t *testing.T) {
+		profile := tempChromiumProfile(t, browser)
+		writeChromeCookieDB(t, profile.cookiesPath(), 24, nil)
+		db, err := sql.Open("sqlite3", profile.cookiesPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`DELETE FROM meta`); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		_, _, err = readChromeCookies(profile)
+		if err == nil || !strings.Contains(err.Error(), "cookie database version") {
+			t.Fatalf("err = %v, want a cookie database version error", err)
+		}
+	})
+}
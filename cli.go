@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// cli is one run of blockblox: where its output goes, the global flags and
+// the account they select.
+type cli struct {
+	ctx            context.Context
+	stdout, stderr io.Writer
+
+	profile string
+	output  string
+	config  configDir
+	verbose bool
+
+	account string
+	named   bool // account was chosen with --profile or BLOCKBLOX_PROFILE
+}
+
+// runFunc runs a command with the arguments left after its flags, and
+// returns the exit status.
+type runFunc func(c *cli, args []string) int
+
+// command is one of blockblox's subcommands.
+type command struct {
+	name    string
+	args    string // its arguments, for the synopsis, e.g. "<time>"
+	summary string // one line for the command list
+	help    string // more for --help; may be empty
+	// flags defines the command's own flags on fs and returns what runs it
+	// once they are parsed.
+	flags func(fs *flag.FlagSet) runFunc
+	// output means the command supports --output json|yaml.
+	output bool
+	// subcommands are completed as its first argument.
+	subcommands []string
+	// hidden commands are left out of the command list.
+	hidden bool
+}
+
+// noFlags is the flags function of a command without flags of its own.
+func noFlags(run runFunc) func(*flag.FlagSet) runFunc {
+	return func(*flag.FlagSet) runFunc { return run }
+}
+
+// commandList returns every command, in the order usage lists them.
+func commandList() []*command {
+	return []*command{
+		{
+			name:    "init",
+			summary: "Extract credentials from your browser (--browser, --profile)",
+			help: "Searches every profile of every supported browser for a Roblox login, saves the\n" +
+				"credentials and shows the account's status. --profile here is a browser profile;\n" +
+				"name the account with 'blockblox --profile <account> init'.",
+			flags: func(fs *flag.FlagSet) runFunc {
+				opts := defineInitFlags(fs)
+				return func(c *cli, _ []string) int { return c.initAccount(opts) }
+			},
+		},
+		{
+			name:    "login",
+			summary: "Save cookies given by hand, from cookies.txt (--cookies) or a HAR file (--har)",
+			help: "Without --cookies or --har, the cookies are asked for at a hidden prompt or read\n" +
+				"from standard input. They are checked with Roblox before they are saved.",
+			flags: func(fs *flag.FlagSet) runFunc {
+				opts := defineLoginFlags(fs)
+				return func(c *cli, args []string) int {
+					if err := runLogin(c.ctx, c.stdout, c.stderr, c.config, c.account, c.verbose, opts, args); err != nil {
+						return c.failed(err)
+					}
+					return 0
+				}
+			},
+		},
+		{
+			name:    "get",
+			summary: "Get current screen time limit",
+			output:  true,
+			flags:   noFlags(appCommand("get", func(a *app, _ []string) int { return a.get() })),
+		},
+		{
+			name:    "set",
+			args:    "<time>",
+			summary: "Set screen time limit (0 = no limit)",
+			help: "<time> is minutes (90, 90min) or hours and minutes (4h, 1.5h, 2 hours, 1h 30m,\n" +
+				"1:30), at most 24h. 0, none, unlimited and off remove the limit.\n" +
+				"+<time> and -<time> change the current limit (+30m, -1h), and\n" +
+				"=consumed+<time> sets it relative to what was played today.\n" +
+				"Changed limits stay between 1 minute and 23h59m; they never remove the limit.",
+			output: true,
+			flags:  noFlags(appCommand("set", (*app).set)),
+		},
+		{
+			name:    "temp",
+			args:    "<time>",
+			summary: "Add temporary screen time (works when screen time exceeded)",
+			help: "<time> is minutes (5) or hours and minutes (15m, 1h, 1:30). Temporary time cannot be\n" +
+				"checked afterwards; it expires silently.",
+			output: true,
+			flags:  noFlags(appCommand("temp", (*app).temp)),
+		},
+		{
+			name:    "week",
+			summary: "Show screen time for the last 7 days",
+			flags:   noFlags(appCommand("week", func(a *app, _ []string) int { return a.week() })),
+		},
+		{
+			name:    "settings",
+			args:    "<subcommand>",
+			summary: "List, show or change any user setting",
+			help: "Subcommands:\n" +
+				"  list                 List every setting and its value\n" +
+				"  get <key>            Show one setting and its options\n" +
+				"  set <key> <value>    Change a setting",
+			subcommands: []string{"list", "get", "set"},
+			flags:       noFlags(appCommand("settings", (*app).settings)),
+		},
+		{
+			name:    "doctor",
+			summary: "Check credentials, connectivity and file permissions",
+			flags: noFlags(func(c *cli, _ []string) int {
+				creds, fromEnv := c.credentials()
+				return runDoctor(c.ctx, c.stdout, c.stderr, c.config, c.account, c.verbose, creds, fromEnv)
+			}),
+		},
+		{
+			name:    "accounts",
+			args:    "<subcommand>",
+			summary: "Manage named accounts (list, add, remove, rename, default)",
+			help: "Subcommands:\n" +
+				"  list                       List accounts and who they sign in as\n" +
+				"  add <name> [init flags]    Extract credentials for a new account\n" +
+				"  remove <name>              Delete an account and its credentials\n" +
+				"  rename <old> <new>         Rename an account\n" +
+				"  default <name>             Use an account when --profile is not given",
+			subcommands: []string{"list", "add", "remove", "rename", "default"},
+			flags: noFlags(func(c *cli, args []string) int {
+				if len(args) > 0 && args[0] == "add" {
+					return c.addAccount(args[1:])
+				}
+				if err := runAccounts(c.stdout, c.stderr, c.config, args); err != nil {
+					return c.failed(err)
+				}
+				return 0
+			}),
+		},
+		{
+			name:    "credentials",
+			args:    "<subcommand>",
+			summary: "Manage saved credentials (migrate)",
+			help: "Subcommands:\n" +
+				"  migrate [--to file|secret-service]    Move saved credentials to an encrypted store",
+			subcommands: []string{"migrate"},
+			flags: noFlags(func(c *cli, args []string) int {
+				if err := runCredentials(c.stdout, c.stderr, c.config, c.account, args); err != nil {
+					return c.failed(err)
+				}
+				return 0
+			}),
+		},
+		{
+			name:    "completion",
+			args:    "<shell>",
+			summary: "Print a shell completion script (bash, zsh or fish)",
+			help: "Load completions for the current shell with:\n" +
+				"  bash:  source <(blockblox completion bash)\n" +
+				"  zsh:   source <(blockblox completion zsh)\n" +
+				"  fish:  blockblox completion fish | source",
+			subcommands: completionShells,
+			flags: noFlags(func(c *cli, args []string) int {
+				if len(args) < 1 {
+					fmt.Fprintln(c.stderr, "Error: missing shell")
+					fmt.Fprintln(c.stderr, "Usage: blockblox completion bash|zsh|fish")
+					return exitUsage
+				}
+				if err := writeCompletion(c.stdout, args[0]); err != nil {
+					return c.failed(err)
+				}
+				return 0
+			}),
+		},
+		{
+			name:    "fake-server",
+			summary: "Run a fake Roblox server for offline testing",
+			flags: func(fs *flag.FlagSet) runFunc {
+				opts := defineFakeServerFlags(fs)
+				return func(c *cli, _ []string) int {
+					if err := runFakeServer(c.ctx, c.stdout, opts); err != nil {
+						return c.failed(err)
+					}
+					return 0
+				}
+			},
+		},
+		{
+			name:    "help",
+			args:    "[command]",
+			summary: "Show help for blockblox or a command",
+			hidden:  true,
+			flags: noFlags(func(c *cli, args []string) int {
+				if len(args) < 1 {
+					printUsage(c.stdout)
+					return 0
+				}
+				cmd := findCommand(args[0])
+				if cmd == nil {
+					fmt.Fprintf(c.stderr, "Unknown command: %s\n", args[0])
+					return exitUsage
+				}
+				fs, _, own := c.flagSet(cmd)
+				printCommandHelp(c.stdout, cmd, fs, own)
+				return 0
+			}),
+		},
+		{
+			name:    "version",
+			summary: "Print the version",
+			hidden:  true,
+			flags: noFlags(func(c *cli, _ []string) int {
+				fmt.Fprintln(c.stdout, version)
+				return 0
+			}),
+		},
+	}
+}
+
+// findCommand returns the command called name, or nil.
+func findCommand(name string) *command {
+	for _, cmd := range commandList() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// globalFlags defines the flags every command takes on fs, except any the
+// command defines itself: init's --profile is a browser profile.
+func (c *cli) globalFlags(fs *flag.FlagSet) {
+	if fs.Lookup("profile") == nil {
+		fs.StringVar(&c.profile, "profile", c.profile, "`account` to use (default: the default account, or "+profileEnv+")")
+	}
+	if fs.Lookup("output") == nil {
+		fs.StringVar(&c.output, "output", c.output, "output `format` for get, set and temp: text, json or yaml")
+	}
+	if fs.Lookup("config") == nil {
+		fs.StringVar((*string)(&c.config), "config", string(c.config), "`directory` for accounts and saved credentials (default: ~/.blockblox, or "+configEnv+")")
+	}
+	if fs.Lookup("verbose") == nil {
+		fs.BoolVar(&c.verbose, "verbose", c.verbose, "log each request to Roblox on standard error")
+	}
+}
+
+// flagSet returns cmd's flags with the global ones, what runs it, and the
+// names of the flags that are its own.
+func (c *cli) flagSet(cmd *command) (*flag.FlagSet, runFunc, map[string]bool) {
+	fs := flag.NewFlagSet("blockblox "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {}
+	run := cmd.flags(fs)
+	own := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) { own[f.Name] = true })
+	c.globalFlags(fs)
+	return fs, run, own
+}
+
+// runCommand parses cmd's flags from args, selects the account and runs it.
+func (c *cli) runCommand(cmd *command, args []string) int {
+	fs, run, own := c.flagSet(cmd)
+	args, rest := splitNegative(fs, args)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandHelp(c.stdout, cmd, fs, own)
+			return 0
+		}
+		fmt.Fprintf(c.stderr, "Run 'blockblox %s --help' for usage\n", cmd.name)
+		return exitUsage
+	}
+	if err := validateOutput(c.output); err != nil {
+		fmt.Fprintf(c.stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if c.output != outputText && !cmd.output {
+		fmt.Fprintf(c.stderr, "Error: --output %s is only supported by get, set and temp\n", c.output)
+		return exitUsage
+	}
+	account, named, err := selectAccount(c.config, c.profile)
+	if err != nil {
+		return c.failed(err)
+	}
+	c.account, c.named = account, named
+	return run(c, append(fs.Args(), rest...))
+}
+
+// splitNegative splits args before the first negative number that is not
+// the value of a flag, so set -5 is read as an argument rather than an
+// unknown flag.
+func splitNegative(fs *flag.FlagSet, args []string) (flags, rest []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' || arg[1] < '0' || arg[1] > '9' {
+			continue
+		}
+		if i > 0 && takesValue(fs, args[i-1]) {
+			continue
+		}
+		return args[:i], args[i:]
+	}
+	return args, nil
+}
+
+// takesValue reports whether arg is a flag in fs that takes the next
+// argument as its value.
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	name := strings.TrimLeft(arg, "-")
+	if name == arg || strings.Contains(name, "=") {
+		return false
+	}
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+// failed reports err and returns the exit status for it.
+func (c *cli) failed(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintf(c.stderr, "Error: %v\n", err)
+	return exitCode(err)
+}
+
+// credentials returns where the account's cookies come from.
+// ROBLOX_SECURITY stands in for the default account, but never for one
+// named explicitly.
+func (c *cli) credentials() (creds roblox.CredentialsSource, fromEnv bool) {
+	if !c.named && os.Getenv("ROBLOX_SECURITY") != "" {
+		return roblox.EnvCredentials(), true
+	}
+	return savedCredentials(c.config, c.account, c.stderr), false
+}
+
+// appCommand makes the command called name, which talks to Roblox as the
+// selected account, run with args.
+func appCommand(name string, run func(a *app, args []string) int) runFunc {
+	return func(c *cli, args []string) int {
+		creds, fromEnv := c.credentials()
+		return c.runApp(name, creds, fromEnv, func(a *app) int { return run(a, args) })
+	}
+}
+
+// initAccount extracts and saves the account's credentials, then shows its
+// status as get does.
+func (c *cli) initAccount(opts *initFlags) int {
+	saved, err := runInit(c.stdout, c.stderr, c.config, c.account, opts)
+	if err != nil {
+		return c.failed(err)
+	}
+	fmt.Fprintln(c.stdout)
+	return c.runApp("get", roblox.StaticCredentials(saved), false, (*app).get)
+}
+
+// runApp runs a command that talks to Roblox with creds, writing its report
+// with --output json|yaml. When BLOCKBLOX_AUTO_REAUTH is set and saved
+// credentials turn out to be expired, the session is renewed from the
+// browser and the command run again.
+func (c *cli) runApp(name string, creds roblox.CredentialsSource, fromEnv bool, run func(a *app) int) int {
+	var extra []roblox.Option
+	if !fromEnv {
+		extra = append(extra, saveRotatedCredentials(c.stderr, c.config, c.account))
+	}
+	client, err := newClient(c.stderr, c.account, c.verbose, creds, extra...)
+	if err != nil {
+		msg, kind := err.Error(), "error"
+		if errors.Is(err, errNoCredentials) {
+			kind = "auth"
+			if c.account == defaultAccount {
+				msg += "\nRun 'blockblox init' to extract credentials from your browser"
+			} else {
+				msg += fmt.Sprintf("\nRun 'blockblox --profile %s init' to extract credentials from your browser", c.account)
+			}
+		}
+		if c.output != outputText {
+			r := newReport(name)
+			r.setError(kind, msg, exitCode(err))
+			writeReport(c.stdout, c.output, r)
+		}
+		fmt.Fprintf(c.stderr, "Error: %s\n", msg)
+		return exitCode(err)
+	}
+
+	a := &app{ctx: c.ctx, stdout: c.stdout, stderr: c.stderr, client: client, out: newReport(name)}
+	if c.output != outputText {
+		a.stdout = io.Discard
+	}
+	if !fromEnv {
+		a.account, a.config = c.account, c.config
+		a.reauth = autoReauthEnabled()
+	}
+	code := run(a)
+	if a.expired && a.reauth {
+		// The session expired: renew it from the browser and try again.
+		a.reauth = false
+		if client, err := reauthenticate(c.ctx, c.stderr, c.config, c.account, c.verbose, client.Credentials()); err != nil {
+			msg := fmt.Sprintf("%s, and renewing it failed: %v", errSessionExpired, err)
+			a.out.setError("auth", msg+"\n"+reinitHint(c.config, c.account), exitAuth)
+			fmt.Fprintf(c.stderr, "Error: %s\n%s\n", msg, reinitHint(c.config, c.account))
+			code = exitAuth
+		} else {
+			a.client, a.expired, a.out = client, false, newReport(name)
+			code = run(a)
+		}
+	}
+	if c.output != outputText {
+		if err := writeReport(c.stdout, c.output, a.out); err != nil {
+			return c.failed(err)
+		}
+	}
+	return code
+}
+
+// printCommandHelp writes cmd's --help: how to call it, what it does, and
+// its flags, own ones first. fs holds both.
+func printCommandHelp(w io.Writer, cmd *command, fs *flag.FlagSet, own map[string]bool) {
+	synopsis := "blockblox " + cmd.name + " [flags]"
+	if cmd.args != "" {
+		synopsis += " " + cmd.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n", synopsis)
+	fmt.Fprintln(w, cmd.summary)
+	if cmd.help != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.help)
+	}
+	if len(own) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, fs, func(name string) bool { return own[name] })
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printFlags(w, fs, func(name string) bool { return !own[name] })
+}
+
+// printFlags lists the flags in fs that include accepts, with what they
+// take and their defaults.
+func printFlags(w io.Writer, fs *flag.FlagSet, include func(name string) bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !include(f.Name) {
+			return
+		}
+		arg, usage := flag.UnquoteUsage(f)
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		if arg != "" {
+			name += " " + arg
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, usage)
+	})
+	tw.Flush()
+}
+
+// printCommands lists the commands that are not hidden, with their
+// arguments unless they are subcommands.
+func printCommands(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, cmd := range commandList() {
+		if cmd.hidden {
+			continue
+		}
+		name := cmd.name
+		if cmd.args != "" && len(cmd.subcommands) == 0 {
+			name += " " + cmd.args
+		}
+		fmt.Fprintf(tw, "  blockblox %s\t%s\n", name, cmd.summary)
+	}
+	tw.Flush()
+}
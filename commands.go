@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// app is what a command needs to run: the request context, where to write,
+// and a client. Commands return the process exit status instead of exiting,
+// so they can be run in-process by tests.
+type app struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+	client *roblox.Client
+
+	// account is the named account whose saved credentials the client
+	// uses, or "" when they came from the environment. config is the
+	// directory its settings are kept in.
+	account string
+	config  configDir
+
+	// expired is set when Roblox refused the saved credentials. With
+	// reauth, that is not reported, as the caller renews them and retries.
+	expired bool
+	reauth  bool
+
+	// out collects what the command found, written in place of its
+	// text by --output json or yaml.
+	out *report
+}
+
+// user returns the signed-in user, remembering who the account signs in as.
+func (a *app) user() (*roblox.UserResponse, error) {
+	user, err := a.client.GetUser(a.ctx)
+	if err == nil {
+		a.out.setUser(user)
+		if a.account != "" {
+			rememberUser(a.config, a.account, user)
+		}
+	}
+	return user, err
+}
+
+// restrictionMessage explains why the account's requests are being refused,
+// with the exit status for it, or returns "" when it is not restricted.
+func (a *app) restrictionMessage() (string, int) {
+	restriction, err := a.client.GetRestriction(a.ctx)
+	if err != nil || restriction == nil {
+		return "", 0
+	}
+	a.out.setRestriction(restriction)
+	switch restriction.Source {
+	case roblox.RestrictionSourceBan:
+		a.out.setError("banned", "account is banned", exitBanned)
+		if ban, err := a.client.GetBanDetails(a.ctx); err == nil {
+			a.out.setBan(ban)
+			return fmt.Sprintf("%s\nReason: %s\nEnds in: %s", ban.PunishmentTypeDescription, ban.MessageToUser, formatTimeUntil(ban.EndDate)), exitBanned
+		}
+		return "Account may be banned. Open roblox.com in a browser to confirm.", exitBanned
+	case roblox.RestrictionSourceScreenTime:
+		a.out.setError("blocked", "screen time limit reached", exitBlocked)
+		return "Screen time limit reached. Use 'blockblox temp <minutes>' to add temporary time.", exitBlocked
+	default:
+		return "", 0
+	}
+}
+
+// restricted records a restriction found before a command could run, and
+// the ban behind it, as the reason the command failed. It returns the exit
+// status for the restriction.
+func (a *app) restricted(restriction *roblox.Restriction, ban *roblox.BanDetails) int {
+	a.out.setRestriction(restriction)
+	if ban != nil {
+		a.out.setBan(ban)
+	}
+	switch restriction.Source {
+	case roblox.RestrictionSourceBan:
+		a.out.setError("banned", "account is banned", exitBanned)
+		return exitBanned
+	case roblox.RestrictionSourceScreenTime:
+		a.out.setError("blocked", "screen time limit reached", exitBlocked)
+		return exitBlocked
+	}
+	return exitError
+}
+
+// usage reports a mistake on the command line: msg, then any lines on how
+// to use the command.
+func (a *app) usage(msg string, lines ...string) int {
+	a.out.setError("usage", msg, exitUsage)
+	fmt.Fprintf(a.stderr, "Error: %s\n", msg)
+	for _, line := range lines {
+		fmt.Fprintln(a.stderr, line)
+	}
+	return exitUsage
+}
+
+// fail reports err and returns an exit status matching its kind. A request
+// cut short by Ctrl-C is reported as an interruption rather than as an API
+// failure, and saved credentials Roblox refuses as an expired session.
+func (a *app) fail(prefix string, err error) int {
+	if errors.Is(a.ctx.Err(), context.Canceled) {
+		a.out.setError("interrupted", "interrupted", exitInterrupted)
+		fmt.Fprintln(a.stderr, "Interrupted")
+		return exitInterrupted
+	}
+	if errors.Is(err, roblox.ErrUnauthorized) && a.account != "" {
+		a.expired = true
+		if !a.reauth {
+			a.out.setError("auth", errSessionExpired.Error()+"\n"+reinitHint(a.config, a.account), exitAuth)
+			fmt.Fprintf(a.stderr, "%s: %s\n%s\n", prefix, errSessionExpired, reinitHint(a.config, a.account))
+		}
+		return exitAuth
+	}
+	a.out.setError(errorKind(err), describeError(err), exitCode(err))
+	if wait := roblox.RetryAfter(err); wait > 0 {
+		a.out.Error.RetryAfterSeconds = int(math.Ceil(wait.Seconds()))
+	}
+	fmt.Fprintf(a.stderr, "%s: %s\n", prefix, describeError(err))
+	return exitCode(err)
+}
+
+func (a *app) get() int {
+	user, err := a.user()
+	if err != nil {
+		if msg, code := a.restrictionMessage(); msg != "" {
+			fmt.Fprintln(a.stderr, msg)
+			return code
+		}
+		return a.fail("Error getting user", err)
+	}
+	fmt.Fprintf(a.stdout, "User: %s (@%s)\n", user.DisplayName, user.Name)
+
+	// Check for restrictions before trying other APIs
+	if restriction, _ := a.client.GetRestriction(a.ctx); restriction != nil {
+		switch restriction.Source {
+		case roblox.RestrictionSourceBan:
+			ban, err := a.client.GetBanDetails(a.ctx)
+			if err == nil {
+				fmt.Fprintf(a.stdout, "\n%s\nReason: %s\nEnds in: %s\n", ban.PunishmentTypeDescription, ban.MessageToUser, formatTimeUntil(ban.EndDate))
+			}
+			return a.restricted(restriction, ban)
+		case roblox.RestrictionSourceScreenTime:
+			fmt.Fprintf(a.stdout, "\nScreen time limit reached.\nResets: %s\n", formatResetTime(restriction.EndTime))
+			fmt.Fprintln(a.stdout, "\nUse 'blockblox temp <minutes>' to add temporary time.")
+			return a.restricted(restriction, nil)
+		}
+	}
+
+	minutes, err := a.client.GetScreenTime(a.ctx)
+	if err != nil {
+		return a.fail("Error getting screen time", err)
+	}
+	if minutes >= 60 && minutes < 1440 {
+		fmt.Fprintf(a.stdout, "Limit: %s (%d minutes)\n", formatDuration(minutes), minutes)
+	} else {
+		fmt.Fprintf(a.stdout, "Limit: %s\n", formatDuration(minutes))
+	}
+
+	consumed, err := a.client.GetTodayConsumption(a.ctx, user.ID)
+	if err != nil {
+		return a.fail("Error getting consumption", err)
+	}
+	a.out.setUsage(minutes, consumed)
+	if consumed >= 60 {
+		fmt.Fprintf(a.stdout, "Consumed: %s (%d minutes)\n", formatMinutes(consumed), consumed)
+	} else {
+		fmt.Fprintf(a.stdout, "Consumed: %s\n", formatMinutes(consumed))
+	}
+	if minutes > 0 && minutes < 1440 {
+		if consumed > minutes {
+			fmt.Fprintf(a.stdout, "Status: Temporary time active (over limit by %s)\n", formatMinutes(consumed-minutes))
+		} else {
+			fmt.Fprintf(a.stdout, "Remaining: %s\n", formatMinutes(minutes-consumed))
+		}
+	} else {
+		fmt.Fprintln(a.stdout, "Remaining: Unlimited")
+	}
+	return 0
+}
+
+func (a *app) set(args []string) int {
+	if len(args) < 1 {
+		return a.usage("missing minutes argument", "Usage: blockblox set <minutes>")
+	}
+
+	change, err := parseLimitChange(args[0])
+	if err != nil {
+		return a.usage(err.Error())
+	}
+
+	minutes := change.minutes
+	if minutes == 0 && !change.relative {
+		minutes = 1440 // 24 hours = no limit
+	}
+
+	user, err := a.user()
+	if err != nil {
+		if msg, code := a.restrictionMessage(); msg != "" {
+			fmt.Fprintln(a.stderr, msg)
+			return code
+		}
+		return a.fail("Error getting user", err)
+	}
+
+	// Check for restrictions before trying to set
+	if restriction, _ := a.client.GetRestriction(a.ctx); restriction != nil {
+		fmt.Fprintf(a.stdout, "User: %s (@%s)\n", user.DisplayName, user.Name)
+		switch restriction.Source {
+		case roblox.RestrictionSourceBan:
+			ban, err := a.client.GetBanDetails(a.ctx)
+			if err == nil {
+				fmt.Fprintf(a.stderr, "\n%s\nReason: %s\nEnds in: %s\n", ban.PunishmentTypeDescription, ban.MessageToUser, formatTimeUntil(ban.EndDate))
+			}
+			return a.restricted(restriction, ban)
+		case roblox.RestrictionSourceScreenTime:
+			fmt.Fprintf(a.stderr, "\nScreen time limit reached.\nResets: %s\n", formatResetTime(restriction.EndTime))
+			fmt.Fprintln(a.stderr, "\nUse 'blockblox temp <minutes>' to add temporary time.")
+			return a.restricted(restriction, nil)
+		}
+	}
+
+	previous := -1
+	if change.relative {
+		if previous, err = a.client.GetScreenTime(a.ctx); err != nil {
+			return a.fail("Error getting screen time", err)
+		}
+		base := previous
+		if change.fromConsumed {
+			if base, err = a.client.GetTodayConsumption(a.ctx, user.ID); err != nil {
+				return a.fail("Error getting consumption", err)
+			}
+		} else if previous == 0 || previous >= 1440 {
+			return a.usage("there is no limit to change", "Set one first, e.g. blockblox set 2h")
+		}
+		// Keep within what Roblox accepts: at least a minute, and short
+		// of 1440, which is no limit. Only set 0 removes the limit.
+		minutes = min(max(base+minutes, 1), 1439)
+		a.out.setPreviousLimit(previous)
+	}
+
+	if err := a.client.SetScreenTime(a.ctx, minutes); err != nil {
+		return a.fail("Error setting screen time", err)
+	}
+	displayMinutes := minutes
+	if minutes >= 1440 {
+		displayMinutes = 0
+	}
+
+	consumed, err := a.client.GetTodayConsumption(a.ctx, user.ID)
+	if err != nil {
+		return a.fail("Error getting consumption", err)
+	}
+	a.out.setUsage(minutes, consumed)
+
+	fmt.Fprintf(a.stdout, "User: %s (@%s)\n", user.DisplayName, user.Name)
+	if previous >= 60 && previous < 1440 {
+		fmt.Fprintf(a.stdout, "Limit was: %s (%d minutes)\n", formatDuration(previous), previous)
+	} else if previous >= 0 {
+		fmt.Fprintf(a.stdout, "Limit was: %s\n", formatDuration(previous))
+	}
+	if minutes >= 60 && minutes < 1440 {
+		fmt.Fprintf(a.stdout, "Limit set to: %s (%d minutes)\n", formatDuration(minutes), minutes)
+	} else {
+		fmt.Fprintf(a.stdout, "Limit set to: %s\n", formatDuration(minutes))
+	}
+	if consumed >= 60 {
+		fmt.Fprintf(a.stdout, "Consumed: %s (%d minutes)\n", formatMinutes(consumed), consumed)
+	} else {
+		fmt.Fprintf(a.stdout, "Consumed: %s\n", formatMinutes(consumed))
+	}
+	if displayMinutes > 0 {
+		if consumed > displayMinutes {
+			fmt.Fprintf(a.stdout, "Status: Temporary time active (over limit by %s)\n", formatMinutes(consumed-displayMinutes))
+		} else {
+			fmt.Fprintf(a.stdout, "Remaining: %s\n", formatMinutes(displayMinutes-consumed))
+		}
+	} else {
+		fmt.Fprintln(a.stdout, "Remaining: Unlimited")
+	}
+	return 0
+}
+
+func (a *app) temp(args []string) int {
+	if len(args) < 1 {
+		return a.usage("missing time argument", "Usage: blockblox temp <time>")
+	}
+
+	minutes, err := parseDuration(args[0])
+	if err != nil {
+		return a.usage(err.Error())
+	}
+
+	if minutes <= 0 {
+		return a.usage("duration must be positive")
+	}
+
+	// Check for ban (temp doesn't work for bans)
+	if restriction, _ := a.client.GetRestriction(a.ctx); restriction != nil && restriction.Source == roblox.RestrictionSourceBan {
+		ban, err := a.client.GetBanDetails(a.ctx)
+		code := a.restricted(restriction, ban)
+		if err == nil {
+			if user, err := a.client.GetUserByID(a.ctx, ban.PunishedUserId); err == nil {
+				a.out.setUser(user)
+				fmt.Fprintf(a.stderr, "User: %s (@%s)\n\n", user.DisplayName, user.Name)
+			}
+			fmt.Fprintf(a.stderr, "%s\nReason: %s\nEnds in: %s\n", ban.PunishmentTypeDescription, ban.MessageToUser, formatTimeUntil(ban.EndDate))
+		} else {
+			fmt.Fprintln(a.stderr, "Account is banned. Open roblox.com in a browser for details.")
+		}
+		return code
+	}
+
+	// Show user info (works via HTML scrape even when blocked)
+	if user, err := a.user(); err == nil {
+		fmt.Fprintf(a.stdout, "User: %s (@%s)\n", user.DisplayName, user.Name)
+	}
+
+	if err := a.client.AddTemporaryScreenTime(a.ctx, minutes); err != nil {
+		return a.fail("Error adding temporary screen time", err)
+	}
+
+	a.out.AddedMinutes = &minutes
+	fmt.Fprintf(a.stdout, "Added %s of temporary screen time\n", formatMinutes(minutes))
+	fmt.Fprintln(a.stdout, "Note: There is no way to check remaining temp time. It expires silently.")
+	return 0
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// completionShells are the shells completion writes scripts for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionCommand is what completion offers for one command, or for
+// blockblox itself before a command is given.
+type completionCommand struct {
+	name, summary string
+	subcommands   []string
+	flags         []completionFlag
+}
+
+// completionFlag is a flag and what to offer as its value.
+type completionFlag struct {
+	name, usage string
+	global      bool
+	takesValue  bool
+	choices     []string
+	files, dirs bool
+}
+
+// writeCompletion writes the completion script for shell. Scripts are
+// built from the command list, so they offer every command, subcommand and
+// flag of this version.
+func writeCompletion(w io.Writer, shell string) error {
+	top, cmds := completionCommands()
+	var b strings.Builder
+	switch shell {
+	case "bash":
+		writeShellCompletion(&b, bashSyntax, top, cmds)
+	case "zsh":
+		writeShellCompletion(&b, zshSyntax, top, cmds)
+	case "fish":
+		writeFishCompletion(&b, top, cmds)
+	default:
+		return usageErrorf("unsupported shell %q (use bash, zsh or fish)", shell)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// completionCommands returns what to offer before a command is given, and
+// for each command.
+func completionCommands() (completionCommand, []completionCommand) {
+	defaults := &cli{output: outputText}
+	top := completionCommand{name: "blockblox"}
+	var cmds []completionCommand
+	for _, cmd := range commandList() {
+		top.subcommands = append(top.subcommands, cmd.name)
+		cc := completionCommand{name: cmd.name, summary: cmd.summary, subcommands: cmd.subcommands}
+		fs, _, own := defaults.flagSet(cmd)
+		fs.VisitAll(func(f *flag.Flag) {
+			cc.flags = append(cc.flags, newCompletionFlag(f, !own[f.Name]))
+		})
+		cmds = append(cmds, cc)
+	}
+	// help completes the name of any command.
+	for i := range cmds {
+		if cmds[i].name == "help" {
+			cmds[i].subcommands = top.subcommands
+		}
+	}
+
+	fs := flag.NewFlagSet("blockblox", flag.ContinueOnError)
+	defaults.globalFlags(fs)
+	fs.Bool("version", false, "print the version")
+	fs.VisitAll(func(f *flag.Flag) {
+		top.flags = append(top.flags, newCompletionFlag(f, true))
+	})
+	return top, cmds
+}
+
+func newCompletionFlag(f *flag.Flag, global bool) completionFlag {
+	_, usage := flag.UnquoteUsage(f)
+	cf := completionFlag{name: f.Name, usage: usage, global: global, takesValue: true}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		cf.takesValue = false
+	}
+	switch f.Name {
+	case "output":
+		cf.choices = []string{outputText, outputJSON, outputYAML}
+	case "browser":
+		cf.choices = browserIDs()
+	case "store":
+		cf.choices = []string{storeFile, storeSecretService, storeEnvFile}
+	case "cookies", "har":
+		cf.files = true
+	case "config":
+		cf.dirs = true
+	}
+	return cf
+}
+
+// shellSyntax is how bash and zsh differ in the script they share.
+type shellSyntax struct {
+	header, footer string
+	locals         string // declares prev, and cur if needed
+	first, cursor  string // index of the first word after blockblox, and of the one being completed
+	word           string // the i'th word
+	set, add       func(words []string) string
+	offer          func(words []string) string
+	offerChoices   string
+	files, dirs    string
+}
+
+var bashSyntax = shellSyntax{
+	header: "# bash completion for blockblox. Load it with:\n#   source <(blockblox completion bash)\n\n",
+	footer: "complete -F _blockblox blockblox\n",
+	locals: "local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}",
+	first:  "1",
+	cursor: "COMP_CWORD",
+	word:   "${COMP_WORDS[i]}",
+	set:    func(words []string) string { return fmt.Sprintf("choices='%s'", strings.Join(words, " ")) },
+	add:    func(words []string) string { return fmt.Sprintf("choices+=' %s'", strings.Join(words, " ")) },
+	offer: func(words []string) string {
+		return fmt.Sprintf(`COMPREPLY=($(compgen -W '%s' -- "$cur"))`, strings.Join(words, " "))
+	},
+	offerChoices: `COMPREPLY=($(compgen -W "$choices" -- "$cur"))`,
+	files:        `COMPREPLY=($(compgen -f -- "$cur"))`,
+	dirs:         `COMPREPLY=($(compgen -d -- "$cur"))`,
+}
+
+var zshSyntax = shellSyntax{
+	header: "#compdef blockblox\n# zsh completion for blockblox. Load it with:\n#   source <(blockblox completion zsh)\n\n",
+	footer: "if [[ $zsh_eval_context[-1] == loadautofunc ]]; then\n\t_blockblox \"$@\"\nelse\n\tcompdef _blockblox blockblox\nfi\n",
+	locals: "local prev=${words[CURRENT-1]}\n\tlocal -a choices",
+	first:  "2",
+	cursor: "CURRENT",
+	word:   "${words[i]}",
+	set:    func(words []string) string { return fmt.Sprintf("choices=(%s)", strings.Join(words, " ")) },
+	add:    func(words []string) string { return fmt.Sprintf("choices+=(%s)", strings.Join(words, " ")) },
+	offer: func(words []string) string {
+		return "compadd -- " + strings.Join(words, " ")
+	},
+	offerChoices: "compadd -- $choices",
+	files:        "_files",
+	dirs:         "_files -/",
+}
+
+// writeShellCompletion writes the bash or zsh script: find the command
+// among the words before the cursor, then offer the value of the flag just
+// before it, or else the command's flags and, for its first argument, its
+// subcommands.
+func writeShellCompletion(b *strings.Builder, sh shellSyntax, top completionCommand, cmds []completionCommand) {
+	valueFlags := map[string]bool{}
+	var skip []string
+	for _, cc := range append([]completionCommand{top}, cmds...) {
+		for _, f := range cc.flags {
+			if f.takesValue && !valueFlags[f.name] {
+				valueFlags[f.name] = true
+				skip = append(skip, "--"+f.name)
+			}
+		}
+	}
+
+	b.WriteString(sh.header)
+	b.WriteString("_blockblox() {\n")
+	fmt.Fprintf(b, "\t%s\n", sh.locals)
+	b.WriteString("\tlocal cmd= args=0 i\n")
+	fmt.Fprintf(b, "\tfor ((i = %s; i < %s; i++)); do\n", sh.first, sh.cursor)
+	fmt.Fprintf(b, "\t\tcase %s in\n", sh.word)
+	fmt.Fprintf(b, "\t\t%s) ((i++)) ;;\n", strings.Join(skip, "|"))
+	b.WriteString("\t\t-*) ;;\n")
+	fmt.Fprintf(b, "\t\t*) if [[ -z $cmd ]]; then cmd=%s; else ((args++)); fi ;;\n", sh.word)
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tdone\n")
+	b.WriteString("\tcase $cmd in\n")
+	writeShellCommand(b, sh, "''", top)
+	for _, cc := range cmds {
+		writeShellCommand(b, sh, cc.name, cc)
+	}
+	b.WriteString("\t*) return ;;\n")
+	b.WriteString("\tesac\n")
+	fmt.Fprintf(b, "\t%s\n", sh.offerChoices)
+	b.WriteString("}\n\n")
+	b.WriteString(sh.footer)
+}
+
+func writeShellCommand(b *strings.Builder, sh shellSyntax, pattern string, cc completionCommand) {
+	fmt.Fprintf(b, "\t%s)\n", pattern)
+	b.WriteString("\t\tcase $prev in\n")
+	var flags []string
+	for _, f := range cc.flags {
+		flags = append(flags, "--"+f.name)
+		if !f.takesValue {
+			continue
+		}
+		switch {
+		case len(f.choices) > 0:
+			fmt.Fprintf(b, "\t\t--%s) %s; return ;;\n", f.name, sh.offer(f.choices))
+		case f.files:
+			fmt.Fprintf(b, "\t\t--%s) %s; return ;;\n", f.name, sh.files)
+		case f.dirs:
+			fmt.Fprintf(b, "\t\t--%s) %s; return ;;\n", f.name, sh.dirs)
+		default:
+			fmt.Fprintf(b, "\t\t--%s) return ;;\n", f.name)
+		}
+	}
+	b.WriteString("\t\tesac\n")
+	if cc.name == "blockblox" {
+		fmt.Fprintf(b, "\t\t%s\n", sh.set(slices.Concat(cc.subcommands, flags)))
+	} else {
+		fmt.Fprintf(b, "\t\t%s\n", sh.set(flags))
+		if len(cc.subcommands) > 0 {
+			fmt.Fprintf(b, "\t\t((args == 0)) && %s\n", sh.add(cc.subcommands))
+		}
+	}
+	b.WriteString("\t\t;;\n")
+}
+
+// writeFishCompletion writes the fish script, which describes each command
+// and flag as well.
+func writeFishCompletion(b *strings.Builder, top completionCommand, cmds []completionCommand) {
+	b.WriteString("# fish completion for blockblox. Load it with:\n#   blockblox completion fish | source\n\n")
+	b.WriteString("complete -c blockblox -f\n")
+	for _, f := range top.flags {
+		cond := ""
+		if f.name == "version" {
+			cond = "__fish_use_subcommand"
+		}
+		writeFishFlag(b, cond, f)
+	}
+	b.WriteString("\n")
+	for _, cc := range cmds {
+		fmt.Fprintf(b, "complete -c blockblox -n __fish_use_subcommand -a %s -d %s\n", cc.name, fishQuote(cc.summary))
+	}
+	for _, cc := range cmds {
+		cond := "__fish_seen_subcommand_from " + cc.name
+		var own []completionFlag
+		for _, f := range cc.flags {
+			if !f.global {
+				own = append(own, f)
+			}
+		}
+		if len(own) == 0 && len(cc.subcommands) == 0 {
+			continue
+		}
+		b.WriteString("\n")
+		for _, f := range own {
+			writeFishFlag(b, cond, f)
+		}
+		if len(cc.subcommands) > 0 {
+			// Stop once one is given; help can name itself.
+			given := slices.DeleteFunc(slices.Clone(cc.subcommands), func(s string) bool { return s == cc.name })
+			fmt.Fprintf(b, "complete -c blockblox -n %s -a %s\n",
+				fishQuote(cond+"; and not __fish_seen_subcommand_from "+strings.Join(given, " ")), fishQuote(strings.Join(cc.subcommands, " ")))
+		}
+	}
+}
+
+func writeFishFlag(b *strings.Builder, cond string, f completionFlag) {
+	b.WriteString("complete -c blockblox")
+	if cond != "" {
+		fmt.Fprintf(b, " -n %s", fishQuote(cond))
+	}
+	fmt.Fprintf(b, " -l %s", f.name)
+	switch {
+	case !f.takesValue:
+	case len(f.choices) > 0:
+		fmt.Fprintf(b, " -x -a %s", fishQuote(strings.Join(f.choices, " ")))
+	case f.files:
+		b.WriteString(" -r -F")
+	case f.dirs:
+		b.WriteString(" -x -a '(__fish_complete_directories)'")
+	default:
+		b.WriteString(" -x")
+	}
+	fmt.Fprintf(b, " -d %s\n", fishQuote(f.usage))
+}
+
+// fishQuote quotes s for fish, where only \ and ' are special inside
+// single quotes.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
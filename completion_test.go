@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestCompletionScripts checks each script parses in its shell, where that
+// shell is installed, and offers every command.
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeCompletion(&b, shell); err != nil {
+				t.Fatal(err)
+			}
+			script := b.String()
+			for _, cmd := range commandList() {
+				if !strings.Contains(script, cmd.name) {
+					t.Errorf("script does not offer %s", cmd.name)
+				}
+			}
+
+			path, err := exec.LookPath(shell)
+			if err != nil {
+				t.Skipf("%s not installed", shell)
+			}
+			check := exec.Command(path, "-n")
+			check.Stdin = strings.NewReader(script)
+			if out, err := check.CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v\n%s", shell, err, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// copyCookieDatabase copies a browser's SQLite cookie database, along with
+// any write-ahead log holding its newest rows, to a temporary directory.
+// Browsers keep the original locked while running. The returned cleanup
+// removes the copy.
+func copyCookieDatabase(path string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "blockblox-cookies-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	tmpPath := filepath.Join(dir, filepath.Base(path))
+	for _, suffix := range []string{"", "-wal"} {
+		input, err := os.ReadFile(path + suffix)
+		if err != nil {
+			if suffix != "" && os.IsNotExist(err) {
+				continue
+			}
+			cleanup()
+			return "", nil, fmt.Errorf("failed to read cookies file: %w", err)
+		}
+		if err := os.WriteFile(tmpPath+suffix, input, 0600); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to copy cookies file: %w", err)
+		}
+	}
+	return tmpPath, cleanup, nil
+}
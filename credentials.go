@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// envFileStore is the plaintext ~/.blockblox.env written by older versions,
+// kept as a fallback for machines with neither a keyring nor a passphrase.
+type envFileStore struct {
+	path string
+}
+
+func (s envFileStore) String() string {
+	return s.path
+}
+
+func (s envFileStore) Exists() bool {
+	return fileExists(s.path)
+}
+
+func (s envFileStore) Load() (roblox.Credentials, error) {
+	vars, err := readEnvFile(s.path)
+	if os.IsNotExist(err) {
+		return roblox.Credentials{}, errNoCredentials
+	} else if err != nil {
+		return roblox.Credentials{}, err
+	}
+	creds := roblox.Credentials{Security: vars["ROBLOX_SECURITY"], BrowserTracker: vars["ROBLOX_BROWSER_TRACKER"]}
+	if creds.Security == "" {
+		return roblox.Credentials{}, fmt.Errorf("ROBLOX_SECURITY missing from %s", s.path)
+	}
+	if creds.BrowserTracker == "" {
+		return roblox.Credentials{}, fmt.Errorf("ROBLOX_BROWSER_TRACKER missing from %s", s.path)
+	}
+	return creds, nil
+}
+
+func (s envFileStore) Save(creds roblox.Credentials) error {
+	content := fmt.Sprintf("ROBLOX_SECURITY=%s\nROBLOX_BROWSER_TRACKER=%s\n", creds.Security, creds.BrowserTracker)
+	return writeFileAtomic(s.path, []byte(content), 0600)
+}
+
+func (s envFileStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readEnvFile parses KEY=value lines, ignoring blanks and # comments.
+func readEnvFile(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		// Remove quotes if present
+		if len(value) >= 2 && ((value[0] == '"' && value[len(value)-1] == '"') ||
+			(value[0] == '\'' && value[len(value)-1] == '\'')) {
+			value = value[1 : len(value)-1]
+		}
+
+		vars[key] = value
+	}
+
+	return vars, scanner.Err()
+}
+
+// initFlags are the flags of init, which accounts add takes too.
+type initFlags struct {
+	browser, profile, store string
+}
+
+func defineInitFlags(fs *flag.FlagSet) *initFlags {
+	f := &initFlags{}
+	fs.StringVar(&f.browser, "browser", "", "`browser` to extract credentials from: "+browserChoices()+" (default: all)")
+	fs.StringVar(&f.profile, "profile", "", "browser `profile` directory or name, e.g. \"Profile 2\"")
+	fs.StringVar(&f.store, "store", "", "where to save credentials: file, secret-service or env (default: secret-service when available, else file)")
+	return f
+}
+
+// runInit extracts credentials from a browser and saves them for account,
+// returning them so the status shown next needs no passphrase.
+func runInit(w, stderr io.Writer, cfg configDir, account string, opts *initFlags) (roblox.Credentials, error) {
+	id := strings.ToLower(opts.browser)
+	if _, ok := browserName(id); !ok {
+		return roblox.Credentials{}, usageErrorf("unknown browser %q (run 'blockblox init --help' for the supported browsers)", opts.browser)
+	}
+	if opts.store != "" {
+		if _, err := newCredentialStore(cfg, account, opts.store, stderr); err != nil {
+			return roblox.Credentials{}, err
+		}
+	}
+
+	chosen, creds, err := extractCredentials(w, id, opts.profile)
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	if err := saveCredentials(w, stderr, cfg, account, creds, opts.store); err != nil {
+		return roblox.Credentials{}, err
+	}
+	if err := rememberBrowser(cfg, account, chosen.id, chosen.dir); err != nil {
+		return roblox.Credentials{}, err
+	}
+	return creds, nil
+}
+
+// reauthenticate renews account's expired session from the browser profile
+// its credentials were last extracted from. The fresh cookies must sign in
+// as the same Roblox user before they replace the saved ones; a client
+// using them is returned; verbose logs its requests as --verbose does.
+func reauthenticate(ctx context.Context, stderr io.Writer, cfg configDir, account string, verbose bool, expired roblox.Credentials) (*roblox.Client, error) {
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	info := config.info(account)
+	if info.Browser == "" {
+		return nil, errors.New("its credentials were not extracted from a browser")
+	}
+	store, err := findCredentialStore(cfg, account, stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(stderr, "%s, renewing it from the browser\n", errSessionExpired)
+	_, creds, err := extractCredentials(stderr, info.Browser, info.BrowserProfile)
+	if err != nil {
+		return nil, err
+	}
+	if creds == expired {
+		return nil, errors.New("the browser has the same expired session")
+	}
+	client, err := newClient(stderr, account, verbose, roblox.StaticCredentials(creds), saveRotatedCredentials(stderr, cfg, account))
+	if err != nil {
+		return nil, err
+	}
+	user, err := client.GetUser(ctx)
+	if errors.Is(err, roblox.ErrUnauthorized) {
+		return nil, errors.New("Roblox did not accept the browser's cookies either")
+	} else if err != nil {
+		return nil, fmt.Errorf("checking the browser's cookies: %s", describeError(err))
+	}
+	if info.UserID != 0 && user.ID != info.UserID {
+		return nil, fmt.Errorf("the browser is signed in as @%s, not @%s", user.Name, info.Username)
+	}
+
+	if err := store.Save(client.Credentials()); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(stderr, "Credentials saved to %s\n\n", store)
+	return client, nil
+}
+
+// extractCredentials reads the Roblox cookies from a browser profile
+// logged into Roblox, narrowed down by browser and profile as for init's
+// --browser and --profile. Several matches are offered to choose from when
+// someone is at the keyboard; otherwise the first is used.
+func extractCredentials(w io.Writer, browser, profile string) (cookieProfile, roblox.Credentials, error) {
+	name, _ := browserName(browser)
+	fmt.Fprintf(w, "Extracting Roblox credentials from %s...\n", name)
+
+	profiles, err := robloxProfiles(browser)
+	if err != nil {
+		return cookieProfile{}, roblox.Credentials{}, err
+	}
+	if profile != "" {
+		var matched []cookieProfile
+		for _, p := range profiles {
+			if p.matches(profile) {
+				matched = append(matched, p)
+			}
+		}
+		if len(matched) == 0 {
+			return cookieProfile{}, roblox.Credentials{}, fmt.Errorf("no %s profile %q is logged into Roblox", name, profile)
+		}
+		profiles = matched
+	}
+
+	var chosen cookieProfile
+	switch {
+	case len(profiles) == 0:
+		return cookieProfile{}, roblox.Credentials{}, fmt.Errorf(".ROBLOSECURITY cookie not found - make sure you're logged into Roblox in %s", name)
+	case len(profiles) == 1:
+		chosen = profiles[0]
+	case isInteractive():
+		if chosen, err = chooseProfile(w, os.Stdin, profiles); err != nil {
+			return cookieProfile{}, roblox.Credentials{}, err
+		}
+	default:
+		chosen = profiles[0]
+		fmt.Fprintf(w, "Several browser profiles are logged into Roblox; using the first (pick another with --browser and --profile):\n")
+		for _, p := range profiles {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+
+	security, browserTracker, err := chosen.read()
+	if err != nil {
+		return cookieProfile{}, roblox.Credentials{}, err
+	}
+
+	fmt.Fprintf(w, "Found credentials in %s\n", chosen)
+	return chosen, roblox.Credentials{Security: security, BrowserTracker: browserTracker}, nil
+}
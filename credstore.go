@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// credentialStoreEnv names the store to use instead of picking one
+// automatically.
+const credentialStoreEnv = "BLOCKBLOX_CREDENTIAL_STORE"
+
+// Credential store names, as accepted by --store and
+// BLOCKBLOX_CREDENTIAL_STORE.
+const (
+	storeFile          = "file"
+	storeSecretService = "secret-service"
+	storeEnvFile       = "env"
+)
+
+// errNoCredentials is returned by a store that holds no credentials.
+var errNoCredentials = errors.New("no saved credentials found")
+
+// credentialStore keeps the Roblox session cookies between runs.
+type credentialStore interface {
+	// String describes where the credentials are kept, for messages.
+	String() string
+	// Load returns errNoCredentials when the store is empty.
+	Load() (roblox.Credentials, error)
+	Save(creds roblox.Credentials) error
+	Delete() error
+	// Exists reports whether the store holds credentials, without
+	// decrypting them.
+	Exists() bool
+}
+
+// newCredentialStore returns the named store for account. Prompts for a
+// passphrase go to w.
+func newCredentialStore(cfg configDir, account, name string, w io.Writer) (credentialStore, error) {
+	dir, err := accountDir(cfg, account)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case storeFile:
+		return fileStore{path: filepath.Join(dir, "credentials.enc"), prompt: w}, nil
+	case storeSecretService:
+		if !secretServiceAvailable() {
+			return nil, errors.New("the Secret Service is not available (secret-tool is missing or cannot reach a keyring)")
+		}
+		return secretServiceStore{account: account}, nil
+	case storeEnvFile:
+		if account == defaultAccount && !cfg.custom() {
+			return envFileStore{path: filepath.Join(filepath.Dir(dir), ".blockblox.env")}, nil
+		}
+		return envFileStore{path: filepath.Join(dir, "credentials.env")}, nil
+	default:
+		return nil, usageErrorf("unknown credential store %q (choose from: %s, %s, %s)", name, storeFile, storeSecretService, storeEnvFile)
+	}
+}
+
+// storeName returns the name of store's kind, as accepted by --store.
+func storeName(store credentialStore) string {
+	switch store.(type) {
+	case fileStore:
+		return storeFile
+	case secretServiceStore:
+		return storeSecretService
+	default:
+		return storeEnvFile
+	}
+}
+
+// credentialStores returns every store usable here for account, encrypted
+// ones first.
+func credentialStores(cfg configDir, account string, w io.Writer) []credentialStore {
+	var stores []credentialStore
+	for _, name := range []string{storeFile, storeSecretService, storeEnvFile} {
+		if store, err := newCredentialStore(cfg, account, name, w); err == nil {
+			stores = append(stores, store)
+		}
+	}
+	return stores
+}
+
+// findCredentialStore returns the store holding account's credentials: the
+// one named by BLOCKBLOX_CREDENTIAL_STORE or the account's settings, or else
+// the first with credentials in it. It returns errNoCredentials when none
+// has any.
+func findCredentialStore(cfg configDir, account string, w io.Writer) (credentialStore, error) {
+	if name := os.Getenv(credentialStoreEnv); name != "" {
+		return newCredentialStore(cfg, account, name, w)
+	}
+	if config, err := loadAccounts(cfg); err == nil {
+		if info := config.Accounts[account]; info != nil && info.Store != "" {
+			if store, err := newCredentialStore(cfg, account, info.Store, w); err == nil && store.Exists() {
+				return store, nil
+			}
+		}
+	}
+	for _, store := range credentialStores(cfg, account, w) {
+		if store.Exists() {
+			return store, nil
+		}
+	}
+	return nil, errNoCredentials
+}
+
+// defaultCredentialStore picks where account's new credentials are saved:
+// the store named by name, BLOCKBLOX_CREDENTIAL_STORE or the account's
+// settings, else the Secret Service, else an encrypted file when a
+// passphrase can be had. The plaintext env file is the last resort, and is
+// reported with a warning on stderr.
+func defaultCredentialStore(cfg configDir, account, name string, stderr io.Writer) (credentialStore, error) {
+	if name == "" {
+		name = os.Getenv(credentialStoreEnv)
+	}
+	if name == "" {
+		if config, err := loadAccounts(cfg); err == nil && config.Accounts[account] != nil {
+			name = config.Accounts[account].Store
+		}
+	}
+	if name != "" {
+		return newCredentialStore(cfg, account, name, stderr)
+	}
+	if secretServiceAvailable() {
+		return newCredentialStore(cfg, account, storeSecretService, stderr)
+	}
+	if _, ok := os.LookupEnv(passphraseEnv); ok || isInteractive() {
+		return newCredentialStore(cfg, account, storeFile, stderr)
+	}
+	fmt.Fprintf(stderr, "Warning: no keyring or passphrase available, so credentials are saved unencrypted.\n")
+	fmt.Fprintf(stderr, "Set %s and run 'blockblox credentials migrate' to encrypt them.\n", passphraseEnv)
+	return newCredentialStore(cfg, account, storeEnvFile, stderr)
+}
+
+// savedCredentials supplies account's saved credentials. Any passphrase
+// prompt goes to stderr.
+func savedCredentials(cfg configDir, account string, stderr io.Writer) roblox.CredentialsSource {
+	return roblox.CredentialsFunc(func() (roblox.Credentials, error) {
+		store, err := findCredentialStore(cfg, account, stderr)
+		if err != nil {
+			return roblox.Credentials{}, err
+		}
+		return store.Load()
+	})
+}
+
+// saveCredentials saves account's creds to the store named by storeName
+// (or the default one), remembering the choice in the account's settings,
+// then removes any plaintext copy left by older versions.
+func saveCredentials(w, stderr io.Writer, cfg configDir, account string, creds roblox.Credentials, storeName string) error {
+	store, err := defaultCredentialStore(cfg, account, storeName, stderr)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(creds); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Credentials saved to %s\n", store)
+	if err := setAccountStore(cfg, account, store); err != nil {
+		return err
+	}
+
+	if _, ok := store.(envFileStore); !ok {
+		legacy, err := newCredentialStore(cfg, account, storeEnvFile, stderr)
+		if err == nil && legacy.Exists() {
+			if err := legacy.Delete(); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Removed plaintext credentials from %s\n", legacy)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data, so a crash never leaves a
+// half-written file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// setAccountStore records store as where account's credentials are kept.
+func setAccountStore(cfg configDir, account string, store credentialStore) error {
+	config, err := loadAccounts(cfg)
+	if err != nil {
+		return err
+	}
+	info := config.info(account)
+	if info.Store == storeName(store) {
+		return nil
+	}
+	info.Store = storeName(store)
+	return config.save()
+}
+
+// moveCredentials moves credentials between two stores of the same kind.
+// Files are renamed rather than re-encrypted, so they keep their passphrase.
+func moveCredentials(source, target credentialStore) error {
+	var from, to string
+	switch s := source.(type) {
+	case fileStore:
+		from, to = s.path, target.(fileStore).path
+	case envFileStore:
+		from, to = s.path, target.(envFileStore).path
+	default:
+		creds, err := source.Load()
+		if err != nil {
+			return err
+		}
+		if err := target.Save(creds); err != nil {
+			return err
+		}
+		return source.Delete()
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+func printCredentialsUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  blockblox credentials migrate [--to file|secret-service]    Move saved credentials to an encrypted store")
+}
+
+// runCredentials manages account's saved credentials.
+func runCredentials(w, stderr io.Writer, cfg configDir, account string, args []string) error {
+	if len(args) < 1 {
+		printCredentialsUsage(stderr)
+		return usageErrorf("missing credentials subcommand")
+	}
+	switch args[0] {
+	case "migrate":
+		return migrateCredentials(w, stderr, cfg, account, args[1:])
+	default:
+		printCredentialsUsage(stderr)
+		return usageErrorf("unknown credentials subcommand: %s", args[0])
+	}
+}
+
+// migrateCredentials moves account's credentials from whichever store holds
+// them into another, by default the first encrypted store available.
+func migrateCredentials(w, stderr io.Writer, cfg configDir, account string, args []string) error {
+	fs := flag.NewFlagSet("credentials migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	to := fs.String("to", "", "store to move credentials to: file or secret-service (default: secret-service when available, else file)")
+	if err := fs.Parse(args); err != nil {
+		return withCode(exitUsage, err)
+	}
+	// Migrating is for encrypting; the plaintext env file is never a target.
+	switch *to {
+	case "", storeFile, storeSecretService:
+	default:
+		printCredentialsUsage(stderr)
+		return usageErrorf("cannot migrate credentials to %q (choose from: %s, %s)", *to, storeFile, storeSecretService)
+	}
+
+	var target credentialStore
+	var err error
+	switch {
+	case *to != "":
+		target, err = newCredentialStore(cfg, account, *to, stderr)
+	case secretServiceAvailable():
+		target, err = newCredentialStore(cfg, account, storeSecretService, stderr)
+	default:
+		target, err = newCredentialStore(cfg, account, storeFile, stderr)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Migrate from the plaintext file first, as that is what needs it.
+	var source credentialStore
+	for _, name := range []string{storeEnvFile, storeFile, storeSecretService} {
+		store, err := newCredentialStore(cfg, account, name, stderr)
+		if err == nil && store.String() != target.String() && store.Exists() {
+			source = store
+			break
+		}
+	}
+	if source == nil {
+		if target.Exists() {
+			fmt.Fprintf(w, "Credentials are already in %s\n", target)
+			return nil
+		}
+		return fmt.Errorf("%w to migrate; run 'blockblox init' first", errNoCredentials)
+	}
+
+	creds, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", source, err)
+	}
+	if err := target.Save(creds); err != nil {
+		return fmt.Errorf("saving to %s: %w", target, err)
+	}
+	// Only remove the old copy once the new one reads back intact.
+	if saved, err := target.Load(); err != nil || saved != creds {
+		return fmt.Errorf("credentials in %s did not read back correctly; %s left in place", target, source)
+	}
+	if err := source.Delete(); err != nil {
+		return err
+	}
+	if err := setAccountStore(cfg, account, target); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Moved credentials from %s to %s\n", source, target)
+	if _, ok := target.(fileStore); ok {
+		fmt.Fprintf(w, "Set %s or enter the passphrase when asked to use them.\n", passphraseEnv)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/astrostl/blockblox/roblox"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// passphraseEnv supplies the encrypted file's passphrase without a prompt.
+const passphraseEnv = "BLOCKBLOX_PASSPHRASE"
+
+var (
+	errNoPassphrase    = errors.New("credentials are encrypted; set " + passphraseEnv + " or run from a terminal to enter the passphrase")
+	errWrongPassphrase = errors.New("wrong passphrase, or the credentials file is damaged")
+)
+
+// Argon2id parameters for new files, following RFC 9106's second
+// recommended option. Files record their own, so these can be raised.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+)
+
+// encryptedFile is the on-disk format of fileStore. The plaintext is the
+// JSON encoding of roblox.Credentials.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        kdf    `json:"kdf"`
+	Cipher     string `json:"cipher"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type kdf struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+func (k kdf) key(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), k.Salt, k.Time, k.Memory, k.Threads, chacha20poly1305.KeySize)
+}
+
+// unlocked remembers the passphrase of each file decrypted or written so
+// far, so saving cookies Roblox rotated mid-command does not ask again.
+var unlocked = map[string]string{}
+
+// fileStore keeps credentials in a file encrypted with XChaCha20-Poly1305
+// under a key derived from a passphrase with Argon2id.
+type fileStore struct {
+	path   string
+	prompt io.Writer // where to ask for the passphrase
+}
+
+func (s fileStore) String() string {
+	return "encrypted file " + s.path
+}
+
+func (s fileStore) Exists() bool {
+	return fileExists(s.path)
+}
+
+func (s fileStore) Load() (roblox.Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return roblox.Credentials{}, errNoCredentials
+	} else if err != nil {
+		return roblox.Credentials{}, err
+	}
+
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return roblox.Credentials{}, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+	if f.Version != 1 || f.KDF.Name != "argon2id" || f.Cipher != "xchacha20-poly1305" {
+		return roblox.Credentials{}, fmt.Errorf("%s uses an unsupported format; upgrade blockblox", s.path)
+	}
+
+	passphrase, err := readPassphrase(s.prompt, false)
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	aead, err := chacha20poly1305.NewX(f.KDF.key(passphrase))
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return roblox.Credentials{}, errWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return roblox.Credentials{}, errWrongPassphrase
+	}
+	unlocked[s.path] = passphrase
+
+	var creds roblox.Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return roblox.Credentials{}, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+	return creds, nil
+}
+
+// Save encrypts creds under a new salt and nonce. A file already unlocked
+// keeps its passphrase; otherwise a new one is asked for.
+func (s fileStore) Save(creds roblox.Credentials) error {
+	passphrase, ok := unlocked[s.path]
+	if !ok {
+		var err error
+		if passphrase, err = readPassphrase(s.prompt, true); err != nil {
+			return err
+		}
+	}
+
+	f := encryptedFile{
+		Version: 1,
+		KDF:     kdf{Name: "argon2id", Salt: make([]byte, 16), Time: argon2Time, Memory: argon2Memory, Threads: argon2Threads},
+		Cipher:  "xchacha20-poly1305",
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(f.KDF.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(f.KDF.key(passphrase))
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	unlocked[s.path] = passphrase
+	return nil
+}
+
+func (s fileStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readPassphrase returns BLOCKBLOX_PASSPHRASE, or asks for the passphrase
+// on w when someone is at the keyboard. confirm asks twice, for a new one.
+func readPassphrase(w io.Writer, confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		if passphrase == "" {
+			return "", fmt.Errorf("%s is empty", passphraseEnv)
+		}
+		return passphrase, nil
+	}
+	if !isInteractive() {
+		return "", errNoPassphrase
+	}
+
+	prompt := "Passphrase for saved credentials: "
+	if confirm {
+		prompt = "New passphrase for saved credentials: "
+	}
+	passphrase, err := readSecret(w, prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := readSecret(w, "Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// secretServiceAvailable reports whether secret-tool can reach the
+// Secret Service. It is a variable so tests never touch a real keyring.
+var secretServiceAvailable = sync.OnceValue(probeSecretService)
+
+// probeSecretService asks secret-tool for an entry to see whether a keyring
+// answers.
+func probeSecretService() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return false
+	}
+	// Finding secret-tool is not enough: over SSH or on a headless box it
+	// is installed but has no session bus or keyring to talk to. A lookup
+	// exits 0 on a match and 1 silently on none; anything else, including
+	// a keyring that doesn't answer in time, means it is unusable.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", "blockblox")
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return exitErr.ExitCode() == 1 && stderr.Len() == 0
+	}
+	return err == nil
+}
+
+// secretServiceStore keeps credentials in the desktop keyring (GNOME
+// Keyring, KeePassXC, ...) through libsecret's secret-tool.
+type secretServiceStore struct {
+	account string
+}
+
+// attributes identify the account's entry.
+func (s secretServiceStore) attributes() []string {
+	return []string{"service", "blockblox", "account", s.account}
+}
+
+func (s secretServiceStore) String() string {
+	if s.account != defaultAccount {
+		return "the Secret Service keyring (" + s.account + ")"
+	}
+	return "the Secret Service keyring"
+}
+
+func (s secretServiceStore) Exists() bool {
+	_, err := s.lookup()
+	return err == nil
+}
+
+func (s secretServiceStore) lookup() ([]byte, error) {
+	output, err := exec.Command("secret-tool", append([]string{"lookup"}, s.attributes()...)...).Output()
+	// secret-tool exits 1 without output when nothing matches.
+	if len(bytes.TrimSpace(output)) == 0 {
+		var exitErr *exec.ExitError
+		if err == nil || errors.As(err, &exitErr) {
+			return nil, errNoCredentials
+		}
+		return nil, fmt.Errorf("secret-tool: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secret-tool: %w", err)
+	}
+	return output, nil
+}
+
+func (s secretServiceStore) Load() (roblox.Credentials, error) {
+	output, err := s.lookup()
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	var creds roblox.Credentials
+	if err := json.Unmarshal(output, &creds); err != nil {
+		return roblox.Credentials{}, fmt.Errorf("reading %s: %w", s, err)
+	}
+	return creds, nil
+}
+
+func (s secretServiceStore) Save(creds roblox.Credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"store", "--label", "blockblox Roblox credentials (" + s.account + ")"}, s.attributes()...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (s secretServiceStore) Delete() error {
+	output, err := exec.Command("secret-tool", append([]string{"clear"}, s.attributes()...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestProbeSecretService(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the Secret Service is only used on Linux")
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"entry found", "echo '{}'; exit 0", true},
+		{"no entry", "exit 1", true},
+		{"no session bus", "echo 'Cannot autolaunch D-Bus without X11 $DISPLAY' >&2; exit 1", false},
+		{"crashed", "exit 2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			script := "#!/bin/sh\n" + tt.script + "\n"
+			if err := os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", dir)
+			if got := probeSecretService(); got != tt.want {
+				t.Errorf("probeSecretService() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("not installed", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		if probeSecretService() {
+			t.Error("probeSecretService() = true without secret-tool")
+		}
+	})
+}
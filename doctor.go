@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// Doctor outcomes, worst last.
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// maxClockSkew is how far the local clock may drift from Roblox's before
+// doctor warns. Date headers only have one second resolution.
+const maxClockSkew = 30 * time.Second
+
+// doctorReport prints one line per check and counts the outcomes.
+type doctorReport struct {
+	w      io.Writer
+	counts map[string]int
+}
+
+// add prints a check's outcome, followed by hint when there is one.
+func (r *doctorReport) add(status, check, detail, hint string) {
+	r.counts[status]++
+	fmt.Fprintf(r.w, "%s  %-17s %s\n", status, check, detail)
+	if hint != "" {
+		fmt.Fprintf(r.w, "      %-17s hint: %s\n", "", hint)
+	}
+}
+
+// runDoctor checks the account's credentials, its standing with Roblox and
+// the files blockblox keeps, and returns exitError if any check failed.
+// fromEnv means creds come from ROBLOX_SECURITY rather than a store, and
+// verbose logs the requests to Roblox.
+func runDoctor(ctx context.Context, w, stderr io.Writer, cfg configDir, account string, verbose bool, creds roblox.CredentialsSource, fromEnv bool) int {
+	r := &doctorReport{w: w, counts: map[string]int{}}
+	fmt.Fprintf(w, "Checking account %s\n\n", account)
+
+	if c, ok := r.checkCredentials(stderr, cfg, account, creds, fromEnv); ok {
+		r.checkCookieFormat(c)
+		var extra []roblox.Option
+		if !fromEnv {
+			extra = append(extra, saveRotatedCredentials(stderr, cfg, account))
+		}
+		client, err := newClient(stderr, account, verbose, roblox.StaticCredentials(c), extra...)
+		if err != nil {
+			r.add(checkFail, "Client", err.Error(), "")
+		} else {
+			r.checkRoblox(ctx, client)
+		}
+	}
+	r.checkFiles(cfg, account, stderr)
+
+	fmt.Fprintf(w, "\n%d passed, %d warning(s), %d failed\n", r.counts[checkPass], r.counts[checkWarn], r.counts[checkFail])
+	if r.counts[checkFail] > 0 {
+		return exitError
+	}
+	return 0
+}
+
+func (r *doctorReport) checkCredentials(stderr io.Writer, cfg configDir, account string, creds roblox.CredentialsSource, fromEnv bool) (roblox.Credentials, bool) {
+	where := "ROBLOX_SECURITY and ROBLOX_BROWSER_TRACKER"
+	if !fromEnv {
+		store, err := findCredentialStore(cfg, account, stderr)
+		if err != nil {
+			r.add(checkFail, "Credentials", err.Error(), initHint(account))
+			return roblox.Credentials{}, false
+		}
+		where = store.String()
+	}
+	c, err := creds.Credentials()
+	switch {
+	case errors.Is(err, errNoPassphrase):
+		r.add(checkFail, "Credentials", "encrypted, and no passphrase was given", "set "+passphraseEnv+" or run doctor from a terminal")
+		return c, false
+	case errors.Is(err, errWrongPassphrase):
+		r.add(checkFail, "Credentials", err.Error(), "check "+passphraseEnv+", or "+initHint(account)+" to save them again")
+		return c, false
+	case err != nil:
+		r.add(checkFail, "Credentials", err.Error(), initHint(account))
+		return c, false
+	}
+	r.add(checkPass, "Credentials", "loaded from "+where, "")
+	return c, true
+}
+
+// initHint names the command that saves fresh credentials for account.
+func initHint(account string) string {
+	if account == defaultAccount {
+		return "run 'blockblox init'"
+	}
+	return fmt.Sprintf("run 'blockblox --profile %s init'", account)
+}
+
+// checkCookieFormat catches cookies copied by hand with a piece missing.
+func (r *doctorReport) checkCookieFormat(c roblox.Credentials) {
+	var problems []string
+	if !strings.HasPrefix(c.Security, "_|WARNING:") {
+		problems = append(problems, ".ROBLOSECURITY does not start with _|WARNING:")
+	}
+	if !strings.Contains(c.BrowserTracker, "browserid=") {
+		problems = append(problems, "RBXEventTrackerV2 has no browserid=")
+	}
+	if len(problems) > 0 {
+		r.add(checkWarn, "Cookie format", strings.Join(problems, "; "), "copy the whole cookie values, or let 'blockblox init' read them from your browser")
+		return
+	}
+	r.add(checkPass, "Cookie format", ".ROBLOSECURITY and RBXEventTrackerV2 look right", "")
+}
+
+// checkRoblox asks Roblox who the credentials belong to and whether they
+// can be used, then reports what the client learned along the way.
+func (r *doctorReport) checkRoblox(ctx context.Context, client *roblox.Client) {
+	user, err := client.GetUser(ctx)
+	switch {
+	case errors.Is(err, roblox.ErrUnauthorized):
+		r.add(checkFail, "Signed in", "Roblox session is invalid or expired", "log into roblox.com in your browser, then run 'blockblox init'")
+		return
+	case err != nil:
+		r.add(checkFail, "Signed in", describeError(err), networkHint(err))
+		return
+	}
+	r.add(checkPass, "Signed in", fmt.Sprintf("as %s (@%s), user %d", user.DisplayName, user.Name, user.ID), "")
+
+	restricted := false
+	restriction, err := client.GetRestriction(ctx)
+	switch {
+	case err != nil:
+		r.add(checkWarn, "Moderation", describeError(err), "")
+	case restriction == nil:
+		r.add(checkPass, "Moderation", "not restricted", "")
+	case restriction.Source == roblox.RestrictionSourceScreenTime:
+		restricted = true
+		r.add(checkWarn, "Moderation", "screen time limit reached, resets "+formatResetTime(restriction.EndTime), "run 'blockblox temp <minutes>' to add time")
+	case restriction.Source == roblox.RestrictionSourceBan:
+		restricted = true
+		detail := "banned"
+		if ban, err := client.GetBanDetails(ctx); err == nil {
+			detail = fmt.Sprintf("%s, ends in %s", ban.PunishmentTypeDescription, formatTimeUntil(ban.EndDate))
+		}
+		r.add(checkFail, "Moderation", detail, "open roblox.com in a browser for details")
+	default:
+		restricted = true
+		r.add(checkWarn, "Moderation", fmt.Sprintf("restricted (source %d)", restriction.Source), "open roblox.com in a browser for details")
+	}
+
+	if !restricted {
+		if _, err := client.GetScreenTime(ctx); errors.Is(err, roblox.ErrRateLimited) {
+			r.add(checkWarn, "Settings", describeError(err), "")
+		} else if err != nil {
+			r.add(checkFail, "Settings", describeError(err), networkHint(err))
+		} else {
+			r.add(checkPass, "Settings", "screen time limit readable", "")
+		}
+	}
+
+	err = client.RefreshCSRFToken(ctx)
+	switch {
+	case err == nil:
+		r.add(checkPass, "CSRF token", "obtained a fresh token", "")
+	case errors.Is(err, roblox.ErrModerated):
+		r.add(checkWarn, "CSRF token", "skipped, Roblox refuses changes while the account is restricted", "")
+	case errors.Is(err, roblox.ErrRateLimited):
+		r.add(checkWarn, "CSRF token", describeError(err), "")
+	default:
+		r.add(checkFail, "CSRF token", describeError(err), networkHint(err))
+	}
+
+	if skew, ok := client.ClockSkew(); !ok {
+		r.add(checkWarn, "Clock", "Roblox sent no Date header to compare with", "")
+	} else if skew.Abs() > maxClockSkew {
+		direction := "ahead of"
+		if skew < 0 {
+			direction = "behind"
+		}
+		r.add(checkWarn, "Clock", fmt.Sprintf("%s %s Roblox's", skew.Abs().Round(time.Second), direction), "turn on automatic time (e.g. 'timedatectl set-ntp true')")
+	} else {
+		r.add(checkPass, "Clock", fmt.Sprintf("within %s of Roblox's", maxClockSkew), "")
+	}
+
+	for _, rl := range client.RateLimits() {
+		window := rl.Window.String()
+		if rl.Window == time.Minute {
+			window = "minute"
+		}
+		detail := fmt.Sprintf("%s: %d of %d requests left per %s", rl.Bucket, rl.Remaining, rl.Limit, window)
+		if rl.Remaining*5 < rl.Limit {
+			hint := ""
+			if rl.RetryAfter > 0 {
+				hint = fmt.Sprintf("wait %d seconds before making changes", int(math.Ceil(rl.RetryAfter.Seconds())))
+			}
+			r.add(checkWarn, "Rate limit", detail, hint)
+		} else {
+			r.add(checkPass, "Rate limit", detail, "")
+		}
+	}
+}
+
+// networkHint suggests what to check when Roblox could not be reached.
+func networkHint(err error) string {
+	var apiErr *roblox.APIError
+	if errors.As(err, &apiErr) || errors.Is(err, roblox.ErrRateLimited) {
+		return ""
+	}
+	if os.Getenv("BLOCKBLOX_API_URL") != "" {
+		return "check BLOCKBLOX_API_URL and that the server is running"
+	}
+	return "check your internet connection"
+}
+
+// checkFiles makes sure nothing blockblox saved is readable by other users.
+func (r *doctorReport) checkFiles(cfg configDir, account string, stderr io.Writer) {
+	var paths []string
+	if dir, err := cfg.path(); err == nil {
+		paths = append(paths, dir)
+	}
+	if path, err := accountsPath(cfg); err == nil {
+		paths = append(paths, path)
+	}
+	for _, store := range credentialStores(cfg, account, stderr) {
+		switch s := store.(type) {
+		case fileStore:
+			paths = append(paths, s.path)
+		case envFileStore:
+			paths = append(paths, s.path)
+		}
+	}
+	if dir, err := accountCacheDir(account); err == nil {
+		paths = append(paths, filepath.Join(dir, "csrf-token"))
+	}
+
+	checked, open := 0, 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		checked++
+		if perm := info.Mode().Perm(); perm&0077 != 0 {
+			open++
+			want := os.FileMode(0600)
+			if info.IsDir() {
+				want = 0700
+			}
+			r.add(checkWarn, "File permissions", fmt.Sprintf("%s is open to other users (%04o)", path, perm), fmt.Sprintf("chmod %o %s", want, path))
+		}
+	}
+	switch {
+	case checked == 0:
+		r.add(checkPass, "File permissions", "nothing saved yet", "")
+	case open == 0:
+		r.add(checkPass, "File permissions", fmt.Sprintf("%d file(s) private to you", checked), "")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// Exit codes for failures a calling script may want to tell apart. They are
+// documented in the README, so existing ones must keep their meaning.
+const (
+	exitError       = 1   // anything not covered below
+	exitUsage       = 2   // mistake on the command line
+	exitAuth        = 3   // credentials missing, locked, expired or refused
+	exitRateLimited = 4   // Roblox asked to slow down; retry later
+	exitNetwork     = 5   // Roblox could not be reached
+	exitBlocked     = 6   // the account reached its screen time limit
+	exitBanned      = 7   // the account is banned
+	exitInterrupted = 130 // Ctrl-C
+)
+
+// codeError gives an error a specific exit status.
+type codeError struct {
+	error
+	code int
+}
+
+func (e *codeError) Unwrap() error { return e.error }
+
+// withCode makes err exit with code, keeping its message.
+func withCode(code int, err error) error {
+	return &codeError{error: err, code: code}
+}
+
+// usageErrorf reports a mistake on the command line.
+func usageErrorf(format string, args ...any) error {
+	return withCode(exitUsage, fmt.Errorf(format, args...))
+}
+
+// errSessionExpired reports that Roblox refused saved credentials, which
+// were good when saved, so the session they belong to has ended.
+var errSessionExpired = errors.New("Roblox session expired")
+
+// autoReauthEnv turns on extracting fresh credentials from the browser an
+// expired session came from, then retrying the command.
+const autoReauthEnv = "BLOCKBLOX_AUTO_REAUTH"
+
+func autoReauthEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(autoReauthEnv))
+	return enabled
+}
+
+// reinitHint tells the user how to replace account's expired credentials,
+// naming the browser profile they were last extracted from.
+func reinitHint(cfg configDir, account string) string {
+	cmd := "blockblox init"
+	if account != defaultAccount {
+		cmd = fmt.Sprintf("blockblox --profile %s init", account)
+	}
+	info := &accountInfo{}
+	if config, err := loadAccounts(cfg); err == nil && config.Accounts[account] != nil {
+		info = config.Accounts[account]
+	}
+	name, ok := browserName(info.Browser)
+	if info.Browser == "" || !ok {
+		return fmt.Sprintf("Log into roblox.com in your browser, then run '%s'", cmd)
+	}
+	profile := info.BrowserProfile
+	if strings.ContainsAny(profile, " '\"") {
+		profile = fmt.Sprintf("%q", profile)
+	}
+	hint := fmt.Sprintf("Log into roblox.com in %s (profile %s), then run '%s --browser %s --profile %s'",
+		name, info.BrowserProfile, cmd, info.Browser, profile)
+	if !autoReauthEnabled() {
+		hint += fmt.Sprintf("\nor set %s=1 to do this automatically", autoReauthEnv)
+	}
+	return hint
+}
+
+// describeError turns a client error into a message for the user. Errors the
+// client could not classify are shown as-is.
+func describeError(err error) string {
+	switch {
+	case errors.Is(err, roblox.ErrUnauthorized):
+		return "Roblox session is invalid or expired\nRun 'blockblox init' to extract fresh credentials from your browser"
+	case errors.Is(err, roblox.ErrRateLimited):
+		if wait := roblox.RetryAfter(err); wait > 0 {
+			return fmt.Sprintf("rate limited by Roblox, retry in %d seconds", int(math.Ceil(wait.Seconds())))
+		}
+		return "rate limited by Roblox, try again in a minute"
+	case errors.Is(err, roblox.ErrModerated):
+		return "account is moderated. Open roblox.com in a browser for details."
+	case errors.Is(err, roblox.ErrCSRFRejected):
+		return "Roblox rejected the request's CSRF token, try again"
+	case errors.Is(err, roblox.ErrUnexpectedSchema):
+		return fmt.Sprintf("%v\nRoblox may have changed its API", err)
+	default:
+		return err.Error()
+	}
+}
+
+// unreachable reports whether err is a request that never got an answer
+// from Roblox: DNS, connection and timeout failures. Other errors from the
+// operating system, such as a missing file, also satisfy net.Error, so the
+// HTTP client's own error type is checked instead.
+func unreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// exitCode picks the process exit status for err.
+func exitCode(err error) int {
+	var coded *codeError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.Is(err, roblox.ErrUnauthorized), errors.Is(err, errSessionExpired),
+		errors.Is(err, errNoCredentials), errors.Is(err, errNoPassphrase), errors.Is(err, errWrongPassphrase):
+		return exitAuth
+	case errors.Is(err, roblox.ErrRateLimited):
+		return exitRateLimited
+	case unreachable(err):
+		return exitNetwork
+	default:
+		return exitError
+	}
+}
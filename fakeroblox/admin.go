@@ -0,0 +1,197 @@
+package fakeroblox
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// State is a snapshot of the fake account, as served by GET /_fake/state.
+type State struct {
+	Now           time.Time `json:"now"`
+	UserID        int64     `json:"userId"`
+	Limit         int       `json:"limit"`
+	PlayedToday   int       `json:"playedToday"`
+	TemporaryTime int       `json:"temporaryTime"`
+	Blocked       bool      `json:"blocked"`
+	Banned        bool      `json:"banned"`
+	SessionValid  bool      `json:"sessionValid"`
+	CSRFToken     string    `json:"csrfToken"`
+	CSRFRotating  bool      `json:"csrfRotating"` // rotated on every POST
+	FailNext      int       `json:"failNext"`     // requests still to fail
+	DropNext      int       `json:"dropNext"`     // connections still to drop
+}
+
+// State returns a snapshot of the fake account.
+func (s *Server) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	today := s.dateKey(0)
+	return State{
+		Now:           s.clock.Now(),
+		UserID:        s.user.ID,
+		Limit:         s.limit,
+		PlayedToday:   s.played[today],
+		TemporaryTime: s.tempTime[today],
+		Blocked:       s.screenTimeBlocked(),
+		Banned:        s.banned(),
+		SessionValid:  !s.expired,
+		CSRFToken:     s.csrfToken,
+		CSRFRotating:  s.csrfAlways,
+		FailNext:      s.failures,
+		DropNext:      s.drops,
+	}
+}
+
+// registerAdmin adds the control endpoints, so a running fake-server can be
+// steered with curl:
+//
+//	GET  /_fake/state
+//	POST /_fake/clock?advance=90m   (or ?set=2025-12-14T21:00:00Z)
+//	POST /_fake/play?minutes=30
+//	POST /_fake/limit?minutes=90
+//	POST /_fake/ban?duration=72h&description=Ban+3+Days&message=...
+//	POST /_fake/unban
+//	POST /_fake/session?expired=true
+//	POST /_fake/session/rotate
+//	POST /_fake/csrf/rotate         (or ?always=true to rotate on every POST)
+//	POST /_fake/fail?status=503&count=2
+//	POST /_fake/drop?count=1
+//
+// Every POST answers with the new state.
+func (s *Server) registerAdmin() {
+	s.mux.HandleFunc("GET /_fake/state", s.handleState)
+	s.mux.HandleFunc("POST /_fake/clock", s.admin(func(r *http.Request) error {
+		q := r.URL.Query()
+		if v := q.Get("set"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return err
+			}
+			s.clock.Set(t)
+		}
+		if v := q.Get("advance"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			s.clock.Advance(d)
+		}
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/play", s.admin(func(r *http.Request) error {
+		minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+		if err != nil {
+			return err
+		}
+		s.Play(minutes)
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/limit", s.admin(func(r *http.Request) error {
+		minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+		if err != nil {
+			return err
+		}
+		s.SetLimit(minutes)
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/ban", s.admin(func(r *http.Request) error {
+		q := r.URL.Query()
+		d, err := time.ParseDuration(q.Get("duration"))
+		if err != nil {
+			return err
+		}
+		description := q.Get("description")
+		if description == "" {
+			description = "Ban 3 Days"
+		}
+		s.Ban(description, q.Get("message"), d)
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/unban", s.admin(func(*http.Request) error {
+		s.Unban()
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/session", s.admin(func(r *http.Request) error {
+		expired, err := strconv.ParseBool(r.URL.Query().Get("expired"))
+		if err != nil {
+			return err
+		}
+		if expired {
+			s.ExpireSession()
+		} else {
+			s.RestoreSession()
+		}
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/session/rotate", s.admin(func(*http.Request) error {
+		s.RotateSession()
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/csrf/rotate", s.admin(func(r *http.Request) error {
+		if v := r.URL.Query().Get("always"); v != "" {
+			always, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			s.RotateCSRFTokenAlways(always)
+			return nil
+		}
+		s.RotateCSRFToken()
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/fail", s.admin(func(r *http.Request) error {
+		q := r.URL.Query()
+		status, err := strconv.Atoi(q.Get("status"))
+		if err != nil {
+			return err
+		}
+		if status < 400 || status > 599 {
+			return fmt.Errorf("status %d is not an error", status)
+		}
+		count, err := queryCount(q)
+		if err != nil {
+			return err
+		}
+		s.FailRequests(count, status)
+		return nil
+	}))
+	s.mux.HandleFunc("POST /_fake/drop", s.admin(func(r *http.Request) error {
+		count, err := queryCount(r.URL.Query())
+		if err != nil {
+			return err
+		}
+		s.DropConnections(count)
+		return nil
+	}))
+}
+
+// queryCount reads ?count=, which defaults to 1.
+func queryCount(q url.Values) (int, error) {
+	if q.Get("count") == "" {
+		return 1, nil
+	}
+	count, err := strconv.Atoi(q.Get("count"))
+	if err == nil && count < 0 {
+		err = fmt.Errorf("count %d is negative", count)
+	}
+	return count, err
+}
+
+func (s *Server) handleState(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.State())
+}
+
+// admin wraps a control action, answering 400 on error and the new state
+// otherwise.
+func (s *Server) admin(action func(*http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := action(r); err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.State())
+	}
+}
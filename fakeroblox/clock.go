@@ -0,0 +1,45 @@
+package fakeroblox
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the fake server's notion of now. It can run with the system clock
+// or be frozen, and either way can be moved forward or set outright.
+type Clock struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	offset time.Duration
+}
+
+// SystemClock returns a Clock that follows the system clock.
+func SystemClock() *Clock {
+	return &Clock{now: time.Now}
+}
+
+// FixedClock returns a Clock frozen at t until it is advanced.
+func FixedClock(t time.Time) *Clock {
+	return &Clock{now: func() time.Time { return t }}
+}
+
+// Now returns the current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now().Add(c.offset)
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = t.Sub(c.now())
+}
@@ -0,0 +1,652 @@
+// Package fakeroblox is an in-memory stand-in for the Roblox APIs used by
+// blockblox. It serves every endpoint the roblox package calls from a single
+// host, so a client built with roblox.SingleHost(url) can be exercised end to
+// end without a network or a real account.
+//
+// The server keeps one account with a daily limit, per-day consumption,
+// temporary time, an optional ban, CSRF tokens and rate limits, all driven by
+// a controllable Clock. It can also inject failures: 5xx responses, dropped
+// connections and a CSRF token that rotates on every POST. State is changed
+// through methods on Server, or over HTTP through the /_fake/ control
+// endpoints (see admin.go).
+package fakeroblox
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// NoLimit is the limit value Roblox uses for "no daily limit".
+const NoLimit = 1440
+
+// Rate limit buckets, matching the limits Roblox documents.
+const (
+	BucketUserSettings   = "user-settings"
+	BucketTempScreenTime = "add-temporary-screentime"
+)
+
+// DefaultUser is the account the server starts with.
+var DefaultUser = roblox.UserResponse{ID: 1234567890, Name: "CoolPlayer123", DisplayName: "Alex"}
+
+// Ban is an active ban on the account.
+type Ban struct {
+	Description string // e.g. "Ban 3 Days"
+	Message     string // reason shown to the user
+	Begin       time.Time
+	End         time.Time
+}
+
+type rateWindow struct {
+	limit  int
+	window time.Duration
+	start  time.Time
+	count  int
+}
+
+// Server is a fake Roblox backend. The zero value is not usable; create one
+// with New.
+type Server struct {
+	mu sync.Mutex
+
+	clock *Clock
+	loc   *time.Location
+	mux   *http.ServeMux
+
+	user        roblox.UserResponse
+	credentials *roblox.Credentials // nil accepts any non-empty cookies
+	expired     bool
+	rotate      bool // hand out a new .ROBLOSECURITY on the next request
+	rotations   int
+	sessions    int
+
+	limit     int
+	played    map[string]int // minutes played per local date
+	tempTime  map[string]int // temporary minutes granted per local date
+	blockedOn string         // local date the account was kicked for screen time
+	ban       *Ban
+	settings  map[string]*setting
+
+	csrfToken  string
+	csrfSerial int
+	csrfAlways bool // rotate the token on every POST
+	rateLimits map[string]*rateWindow
+
+	failures   int // answer this many requests with failStatus
+	failStatus int
+	drops      int            // handle this many requests, then reset the connection
+	requests   map[string]int // "METHOD /path" of every API request received
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithClock sets the server's clock. The default is SystemClock.
+func WithClock(c *Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// WithLocation sets the time zone that decides where one day of screen time
+// ends and the next begins. The default is time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Server) {
+		s.loc = loc
+	}
+}
+
+// WithUser sets the account the server plays.
+func WithUser(u roblox.UserResponse) Option {
+	return func(s *Server) {
+		s.user = u
+	}
+}
+
+// WithCredentials makes the server accept only these cookies. By default any
+// non-empty .ROBLOSECURITY is accepted.
+func WithCredentials(c roblox.Credentials) Option {
+	return func(s *Server) {
+		s.credentials = &c
+	}
+}
+
+// WithLimit sets the starting daily limit in minutes.
+func WithLimit(minutes int) Option {
+	return func(s *Server) {
+		s.limit = minutes
+	}
+}
+
+// New creates a Server.
+func New(opts ...Option) *Server {
+	s := &Server{
+		clock:    SystemClock(),
+		loc:      time.Local,
+		user:     DefaultUser,
+		limit:    NoLimit,
+		played:   map[string]int{},
+		tempTime: map[string]int{},
+		settings: defaultSettings(),
+		requests: map[string]int{},
+		rateLimits: map[string]*rateWindow{
+			BucketUserSettings:   {limit: 30, window: time.Minute},
+			BucketTempScreenTime: {limit: 5, window: time.Minute},
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.rotateCSRFToken()
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /user-settings-api/v1/user-settings/settings-and-options", s.handleSettings)
+	s.mux.HandleFunc("POST /user-settings-api/v1/user-settings", s.handleUpdateSettings)
+	s.mux.HandleFunc("GET /v1/users/authenticated", s.handleAuthenticatedUser)
+	s.mux.HandleFunc("GET /v1/users/{id}", s.handleUserByID)
+	s.mux.HandleFunc("GET /parental-controls-api/v1/parental-controls/get-weekly-screentime", s.handleWeeklyScreentime)
+	s.mux.HandleFunc("POST /parental-controls-api/v1/parental-controls/add-temporary-screentime", s.handleAddTemporaryScreenTime)
+	s.mux.HandleFunc("GET /v2/not-approved", s.handleRestriction)
+	s.mux.HandleFunc("GET /v1/not-approved", s.handleBanDetails)
+	s.mux.HandleFunc("GET /not-approved", s.handleNotApprovedPage)
+	s.registerAdmin()
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Date follows the fake clock, as clients compare it with their own.
+	w.Header().Set("Date", s.clock.Now().UTC().Format(http.TimeFormat))
+	if !strings.HasPrefix(r.URL.Path, "/_fake/") && s.inject(w, r) {
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// inject counts an API request and applies any failure queued for it. It
+// reports whether it answered the request.
+func (s *Server) inject(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	fail, drop, status := false, false, s.failStatus
+	switch {
+	case s.failures > 0:
+		s.failures--
+		fail = true
+	case s.drops > 0:
+		s.drops--
+		drop = true
+	}
+	s.mu.Unlock()
+
+	switch {
+	case fail:
+		writeErrors(w, status, http.StatusText(status))
+		return true
+	case drop:
+		// The request takes effect, but the response never arrives, as
+		// when a connection fails after the request was sent.
+		s.mux.ServeHTTP(httptest.NewRecorder(), r)
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				if tcp, ok := conn.(*net.TCPConn); ok {
+					tcp.SetLinger(0) // reset rather than close cleanly
+				}
+				conn.Close()
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Clock returns the server's clock.
+func (s *Server) Clock() *Clock {
+	return s.clock
+}
+
+// Credentials returns cookies the server accepts.
+func (s *Server) Credentials() roblox.Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentCredentials()
+}
+
+// currentCredentials must be called with s.mu held.
+func (s *Server) currentCredentials() roblox.Credentials {
+	if s.credentials != nil {
+		return *s.credentials
+	}
+	return roblox.Credentials{
+		Security:       "_|WARNING:-DO-NOT-SHARE-THIS.--Sharing-this-will-allow-someone-to-log-in-as-you-and-to-steal-your-ROBUX-and-items.|_FAKE",
+		BrowserTracker: "CreateDate=1/1/2025 12:00:00 PM&rbxid=&browserid=1",
+	}
+}
+
+// SetLimit sets the daily limit in minutes. NoLimit (or 0) removes it.
+func (s *Server) SetLimit(minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = minutes
+}
+
+// Limit returns the daily limit in minutes.
+func (s *Server) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// Play records minutes of play today. Like Roblox, the account is only
+// locked out while playing: once today's play reaches the limit plus any
+// temporary time, it stays blocked until midnight, more temporary time is
+// granted, or the limit is raised.
+func (s *Server) Play(minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	today := s.dateKey(0)
+	s.played[today] += minutes
+	if s.overLimit() {
+		s.blockedOn = today
+	}
+}
+
+// SetPlayed sets the minutes played daysAgo days ago (0 is today).
+func (s *Server) SetPlayed(daysAgo, minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.played[s.dateKey(daysAgo)] = minutes
+}
+
+// TemporaryTime returns the temporary minutes granted today.
+func (s *Server) TemporaryTime() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tempTime[s.dateKey(0)]
+}
+
+// AddTemporaryTime grants temporary minutes for today, as the
+// add-temporary-screentime endpoint does.
+func (s *Server) AddTemporaryTime(minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addTemporaryTime(minutes)
+}
+
+func (s *Server) addTemporaryTime(minutes int) {
+	today := s.dateKey(0)
+	if s.screenTimeBlocked() {
+		// Temporary time starts counting from the moment it is granted.
+		s.tempTime[today] = s.played[today] - s.limit
+	}
+	s.tempTime[today] += minutes
+}
+
+// Ban bans the account for d, starting now.
+func (s *Server) Ban(description, message string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.ban = &Ban{Description: description, Message: message, Begin: now, End: now.Add(d)}
+}
+
+// Unban lifts any ban.
+func (s *Server) Unban() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ban = nil
+}
+
+// ExhaustRateLimit spends what is left of bucket's budget, so the next
+// request in it is answered with 429.
+func (s *Server) ExhaustRateLimit(bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rw, ok := s.rateLimits[bucket]; ok {
+		rw.start, rw.count = s.clock.Now(), rw.limit
+	}
+}
+
+// ExpireSession makes every authenticated request fail with 401 until
+// RestoreSession is called.
+func (s *Server) ExpireSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired = true
+}
+
+// RotateSession makes the next authenticated request answer with a new
+// .ROBLOSECURITY in Set-Cookie, as Roblox does from time to time. From then
+// on only the new cookie is accepted.
+func (s *Server) RotateSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate = true
+}
+
+// SignIn starts a new session, as logging into roblox.com again does, and
+// returns its cookies. Only they are accepted from then on, even if the
+// session had expired.
+func (s *Server) SignIn() roblox.Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions++
+	creds := s.currentCredentials()
+	creds.Security = fmt.Sprintf("%s-SESSION%d", creds.Security, s.sessions)
+	s.credentials = &creds
+	s.expired = false
+	return creds
+}
+
+// RestoreSession undoes ExpireSession.
+func (s *Server) RestoreSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired = false
+}
+
+// RotateCSRFToken invalidates the current CSRF token, as Roblox does
+// periodically.
+func (s *Server) RotateCSRFToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotateCSRFToken()
+}
+
+// RotateCSRFTokenAlways makes every POST rotate the CSRF token before it is
+// checked, so every token a client sends is stale, or stops doing so.
+func (s *Server) RotateCSRFTokenAlways(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.csrfAlways = on
+}
+
+// FailRequests answers the next n API requests with status, typically a
+// 5xx, without handling them.
+func (s *Server) FailRequests(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures, s.failStatus = n, status
+}
+
+// DropConnections handles the next n API requests and then resets the
+// connection instead of answering, so the client cannot tell whether they
+// took effect.
+func (s *Server) DropConnections(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drops = n
+}
+
+// Requests returns how many requests with method reached path, including
+// ones failed or dropped on purpose.
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+// SetRateLimit changes the budget of a rate limit bucket and resets it.
+func (s *Server) SetRateLimit(bucket string, limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimits[bucket] = &rateWindow{limit: limit, window: window}
+}
+
+func (s *Server) rotateCSRFToken() {
+	s.csrfSerial++
+	s.csrfToken = fmt.Sprintf("fake-csrf-%d", s.csrfSerial)
+}
+
+// dateKey identifies the local day daysAgo days before today.
+func (s *Server) dateKey(daysAgo int) string {
+	return s.clock.Now().In(s.loc).AddDate(0, 0, -daysAgo).Format(time.DateOnly)
+}
+
+// nextMidnight is when today's screen time block lifts.
+func (s *Server) nextMidnight() time.Time {
+	now := s.clock.Now().In(s.loc)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, s.loc)
+}
+
+// banned and screenTimeBlocked must be called with s.mu held.
+
+func (s *Server) banned() bool {
+	return s.ban != nil && s.clock.Now().Before(s.ban.End)
+}
+
+func (s *Server) screenTimeBlocked() bool {
+	return s.blockedOn == s.dateKey(0) && s.overLimit()
+}
+
+func (s *Server) overLimit() bool {
+	if s.limit <= 0 || s.limit >= NoLimit {
+		return false
+	}
+	today := s.dateKey(0)
+	return s.played[today] >= s.limit+s.tempTime[today]
+}
+
+func (s *Server) moderated() bool {
+	return s.banned() || s.screenTimeBlocked()
+}
+
+// writeJSON writes v with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeErrors writes a Roblox-style {"errors":[...]} body.
+func writeErrors(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"errors": []roblox.ErrorDetail{{Code: 0, Message: message}},
+	})
+}
+
+// authorize checks the session cookies and, unless allowModerated, that the
+// account is not moderated. It writes the failure and returns false when the
+// request must stop. It must be called with s.mu held.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, allowModerated bool) bool {
+	security, err := r.Cookie(".ROBLOSECURITY")
+	ok := err == nil && security.Value != "" && !s.expired
+	if ok && s.credentials != nil {
+		tracker, err := r.Cookie("RBXEventTrackerV2")
+		ok = security.Value == s.credentials.Security && err == nil && tracker.Value == s.credentials.BrowserTracker
+	}
+	if !ok {
+		writeErrors(w, http.StatusUnauthorized, "Authorization has been denied for this request.")
+		return false
+	}
+	if s.rotate {
+		s.rotate = false
+		s.rotations++
+		creds := s.currentCredentials()
+		creds.Security = fmt.Sprintf("%s-ROTATED%d", strings.Split(creds.Security, "-ROTATED")[0], s.rotations)
+		s.credentials = &creds
+		http.SetCookie(w, &http.Cookie{Name: ".ROBLOSECURITY", Value: creds.Security, Path: "/", HttpOnly: true, MaxAge: 365 * 24 * 60 * 60})
+	}
+	if !allowModerated && s.moderated() {
+		writeErrors(w, http.StatusForbidden, "User is moderated")
+		return false
+	}
+	return true
+}
+
+// checkCSRF implements Roblox's CSRF dance: a POST without the current token
+// is refused with 403 and handed the token to retry with. It must be called
+// with s.mu held.
+func (s *Server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if s.csrfAlways {
+		s.rotateCSRFToken()
+	}
+	if r.Header.Get("X-Csrf-Token") != s.csrfToken {
+		w.Header().Set("X-Csrf-Token", s.csrfToken)
+		writeErrors(w, http.StatusForbidden, "Token Validation Failed")
+		return false
+	}
+	return true
+}
+
+// spend takes one request from bucket, writing the X-Ratelimit-* headers and,
+// when the budget is gone, a 429. It must be called with s.mu held.
+func (s *Server) spend(w http.ResponseWriter, bucket string) bool {
+	rw, ok := s.rateLimits[bucket]
+	if !ok || rw.limit <= 0 {
+		return true
+	}
+	now := s.clock.Now()
+	if rw.start.IsZero() || !now.Before(rw.start.Add(rw.window)) {
+		rw.start, rw.count = now, 0
+	}
+	reset := int(rw.start.Add(rw.window).Sub(now).Seconds())
+
+	h := w.Header()
+	h.Set("X-Ratelimit-Limit", fmt.Sprintf("%d, %d;w=%d", rw.limit, rw.limit, int(rw.window.Seconds())))
+	h.Set("X-Ratelimit-Reset", strconv.Itoa(reset))
+	if rw.count >= rw.limit {
+		h.Set("X-Ratelimit-Remaining", "0")
+		h.Set("Retry-After", strconv.Itoa(reset))
+		writeErrors(w, http.StatusTooManyRequests, "Too many requests")
+		return false
+	}
+	rw.count++
+	h.Set("X-Ratelimit-Remaining", strconv.Itoa(rw.limit-rw.count))
+	return true
+}
+
+func (s *Server) handleAuthenticatedUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, false) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.user)
+}
+
+func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id != s.user.ID {
+		writeErrors(w, http.StatusNotFound, "The user id is invalid.")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.user)
+}
+
+func (s *Server) handleWeeklyScreentime(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, false) {
+		return
+	}
+	if r.URL.Query().Get("userId") != strconv.FormatInt(s.user.ID, 10) {
+		writeErrors(w, http.StatusForbidden, "Unauthorized user")
+		return
+	}
+
+	days := make([]roblox.DailyScreentime, 7)
+	for i := range days {
+		days[i] = roblox.DailyScreentime{DaysAgo: i, MinutesPlayed: s.played[s.dateKey(i)]}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"dailyScreentimes": days,
+		"localDayOfWeek":   int(s.clock.Now().In(s.loc).Weekday()),
+	})
+}
+
+func (s *Server) handleAddTemporaryScreenTime(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, true) || !s.checkCSRF(w, r) || !s.spend(w, BucketTempScreenTime) {
+		return
+	}
+
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Minutes <= 0 {
+		writeErrors(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	s.addTemporaryTime(req.Minutes)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRestriction(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, true) {
+		return
+	}
+
+	now := s.clock.Now()
+	var restriction *roblox.Restriction
+	switch {
+	case s.banned():
+		restriction = &roblox.Restriction{
+			Source:           roblox.RestrictionSourceBan,
+			ModerationStatus: 2,
+			StartTime:        s.ban.Begin.UTC().Format(time.RFC3339Nano),
+			EndTime:          s.ban.End.UTC().Format(time.RFC3339Nano),
+			DurationSeconds:  int(s.ban.End.Sub(now).Seconds()),
+		}
+	case s.screenTimeBlocked():
+		end := s.nextMidnight()
+		restriction = &roblox.Restriction{
+			Source:           roblox.RestrictionSourceScreenTime,
+			ModerationStatus: 2,
+			StartTime:        now.UTC().Format(time.RFC3339Nano),
+			EndTime:          end.UTC().Format(time.RFC3339),
+			DurationSeconds:  int(end.Sub(now).Seconds()),
+		}
+	}
+	writeJSON(w, http.StatusOK, roblox.RestrictionResponse{Restriction: restriction})
+}
+
+func (s *Server) handleBanDetails(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, true) {
+		return
+	}
+	if !s.banned() {
+		writeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"punishedUserId":            s.user.ID,
+		"messageToUser":             s.ban.Message,
+		"punishmentTypeDescription": s.ban.Description,
+		"beginDate":                 s.ban.Begin.UTC().Format(time.RFC3339Nano),
+		"endDate":                   s.ban.End.UTC().Format(time.RFC3339Nano),
+		"badUtterances":             []any{},
+	})
+}
+
+func (s *Server) handleNotApprovedPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, true) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><meta name="user-data" data-userid="%d" data-name="%s" data-displayname="%s"></head>
+<body><h1>Not Approved</h1></body>
+</html>
+`, s.user.ID, html.EscapeString(s.user.Name), html.EscapeString(s.user.DisplayName))
+}
@@ -0,0 +1,160 @@
+package fakeroblox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	userPath     = "/v1/users/authenticated"
+	tempTimePath = "/parental-controls-api/v1/parental-controls/add-temporary-screentime"
+)
+
+// send makes an authenticated request to the server, with csrf as the token
+// when it is not empty.
+func send(t *testing.T, s *Server, url, method, path, csrf string) (*http.Response, error) {
+	t.Helper()
+	body := ""
+	if method == http.MethodPost {
+		body = `{"minutes":15}`
+	}
+	req, err := http.NewRequest(method, url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := s.Credentials()
+	req.AddCookie(&http.Cookie{Name: ".ROBLOSECURITY", Value: creds.Security})
+	req.AddCookie(&http.Cookie{Name: "RBXEventTrackerV2", Value: creds.BrowserTracker})
+	if csrf != "" {
+		req.Header.Set("X-Csrf-Token", csrf)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestFailRequests(t *testing.T) {
+	s := New()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	s.FailRequests(2, http.StatusServiceUnavailable)
+	for i, want := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+		resp, err := send(t, s, ts.URL, http.MethodGet, userPath, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+	if got := s.Requests(http.MethodGet, userPath); got != 3 {
+		t.Errorf("Requests = %d, want 3", got)
+	}
+}
+
+func TestDropConnections(t *testing.T) {
+	s := New()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	s.DropConnections(1)
+	if _, err := send(t, s, ts.URL, http.MethodPost, tempTimePath, s.State().CSRFToken); err == nil {
+		t.Fatal("dropped request succeeded")
+	}
+	if got := s.TemporaryTime(); got != 15 {
+		t.Errorf("dropped request granted %d minutes, want 15", got)
+	}
+	resp, err := send(t, s, ts.URL, http.MethodPost, tempTimePath, s.State().CSRFToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status %d after the drop, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if got := s.Requests(http.MethodPost, tempTimePath); got != 2 {
+		t.Errorf("Requests = %d, want 2", got)
+	}
+}
+
+func TestRotateCSRFTokenAlways(t *testing.T) {
+	s := New()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	s.RotateCSRFTokenAlways(true)
+	token := s.State().CSRFToken
+	for i := range 3 {
+		resp, err := send(t, s, ts.URL, http.MethodPost, tempTimePath, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := resp.Header.Get("X-Csrf-Token")
+		if resp.StatusCode != http.StatusForbidden || next == "" || next == token {
+			t.Fatalf("POST %d with the latest token: status %d, token %q", i+1, resp.StatusCode, next)
+		}
+		token = next
+	}
+
+	s.RotateCSRFTokenAlways(false)
+	resp, err := send(t, s, ts.URL, http.MethodPost, tempTimePath, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status %d once rotation stops, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestAdminFailureControls(t *testing.T) {
+	s := New()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		check  func(State) bool
+	}{
+		{"/_fake/fail?status=502&count=2", http.StatusOK, func(st State) bool { return st.FailNext == 2 }},
+		{"/_fake/fail?status=200", http.StatusBadRequest, nil},
+		{"/_fake/fail?status=503&count=-1", http.StatusBadRequest, nil},
+		{"/_fake/drop", http.StatusOK, func(st State) bool { return st.DropNext == 1 }},
+		{"/_fake/csrf/rotate?always=true", http.StatusOK, func(st State) bool { return st.CSRFRotating }},
+		{"/_fake/csrf/rotate?always=maybe", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(ts.URL+tt.path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st State
+		json.NewDecoder(resp.Body).Decode(&st)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("POST %s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.check != nil && !tt.check(st) {
+			t.Errorf("POST %s: state %+v", tt.path, st)
+		}
+	}
+
+	// Control endpoints are never failed themselves.
+	resp, err := http.Get(ts.URL + "/_fake/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /_fake/state with failures queued: status %d", resp.StatusCode)
+	}
+	if resp, _ := send(t, s, ts.URL, http.MethodGet, userPath, ""); resp == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Error("queued failure was not applied to the API")
+	}
+}
@@ -0,0 +1,193 @@
+package fakeroblox
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// setting is a catalog entry other than the daily limit, which lives in
+// Server.limit.
+type setting struct {
+	value   json.RawMessage
+	options []roblox.SettingOption
+}
+
+// option builds a fixed-value option.
+func option(value any, requirement string) roblox.SettingOption {
+	raw, _ := json.Marshal(value)
+	return roblox.SettingOption{Option: roblox.OptionValue{OptionValue: raw}, Requirement: requirement}
+}
+
+// typedOption builds a free-form option of optionType.
+func typedOption(optionType, requirement string) roblox.SettingOption {
+	return roblox.SettingOption{Option: roblox.OptionValue{OptionType: optionType}, Requirement: requirement}
+}
+
+// defaultSettings is a representative slice of a teen account's catalog: a
+// few settings the account may change itself, one that needs a parent and
+// one with a mix.
+func defaultSettings() map[string]*setting {
+	self := roblox.RequirementSelfUpdate
+	audience := func(values ...string) []roblox.SettingOption {
+		opts := make([]roblox.SettingOption, len(values))
+		for i, v := range values {
+			opts[i] = option(v, self)
+		}
+		return opts
+	}
+	return map[string]*setting{
+		"whoCanChatWithMeInApp": {
+			value:   json.RawMessage(`"Friends"`),
+			options: audience("AllUsers", "Friends", "NoOne"),
+		},
+		"whoCanJoinMeInExperiences": {
+			value:   json.RawMessage(`"Friends"`),
+			options: audience("AllUsers", "FriendsAndFollowing", "Friends", "NoOne"),
+		},
+		"whoCanSeeMyInventory": {
+			value:   json.RawMessage(`"AllUsers"`),
+			options: audience("AllUsers", "Friends", "NoOne"),
+		},
+		"allowEnableGroupNotifications": {
+			value:   json.RawMessage(`true`),
+			options: []roblox.SettingOption{typedOption(roblox.OptionTypeBoolean, self)},
+		},
+		"contentAgeRestriction": {
+			value: json.RawMessage(`"NinePlus"`),
+			options: []roblox.SettingOption{
+				option("NinePlus", "ParentalConsent"),
+				option("ThirteenPlus", "ParentalConsent"),
+			},
+		},
+		"monthlySpendLimit": {
+			value: json.RawMessage(`0`),
+			options: []roblox.SettingOption{
+				typedOption(roblox.OptionTypeInteger, "ParentalConsent"),
+			},
+		},
+	}
+}
+
+// SetSetting sets the current value of a catalog setting, creating it with
+// no options if it does not exist.
+func (s *Server) SetSetting(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, _ := json.Marshal(value)
+	if st, ok := s.settings[key]; ok {
+		st.value = raw
+		return
+	}
+	s.settings[key] = &setting{value: raw}
+}
+
+// Setting returns the current value of a catalog setting as JSON.
+func (s *Server) Setting(key string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == roblox.SettingDailyScreenTimeLimit {
+		raw, _ := json.Marshal(s.limit)
+		return raw
+	}
+	if st, ok := s.settings[key]; ok {
+		return st.value
+	}
+	return nil
+}
+
+// catalog renders settings-and-options. It must be called with s.mu held.
+func (s *Server) catalog() roblox.Settings {
+	limit, _ := json.Marshal(s.limit)
+	out := roblox.Settings{
+		roblox.SettingDailyScreenTimeLimit: {
+			CurrentValue: limit,
+			Options:      []roblox.SettingOption{typedOption(roblox.OptionTypeInteger, roblox.RequirementSelfUpdate)},
+		},
+	}
+	for key, st := range s.settings {
+		out[key] = roblox.Setting{CurrentValue: st.value, Options: st.options}
+	}
+	return out
+}
+
+// allowed reports whether the account may set value through one of options.
+func allowed(options []roblox.SettingOption, value json.RawMessage) (ok, permitted bool) {
+	for _, opt := range options {
+		var match bool
+		switch opt.Option.OptionType {
+		case roblox.OptionTypeInteger:
+			var n int
+			match = json.Unmarshal(value, &n) == nil
+		case roblox.OptionTypeBoolean:
+			var b bool
+			match = json.Unmarshal(value, &b) == nil
+		default:
+			var want, got bytes.Buffer
+			match = json.Compact(&want, opt.Option.OptionValue) == nil &&
+				json.Compact(&got, value) == nil && want.String() == got.String()
+		}
+		if match {
+			ok = true
+			permitted = permitted || opt.Requirement == roblox.RequirementSelfUpdate
+		}
+	}
+	return ok, permitted
+}
+
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, false) || !s.spend(w, BucketUserSettings) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.catalog())
+}
+
+func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.authorize(w, r, false) || !s.checkCSRF(w, r) || !s.spend(w, BucketUserSettings) {
+		return
+	}
+
+	var req map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate everything before changing anything.
+	catalog := s.catalog()
+	for key, value := range req {
+		st, ok := catalog[key]
+		if !ok {
+			writeErrors(w, http.StatusBadRequest, "Invalid setting: "+key)
+			return
+		}
+		valid, permitted := allowed(st.Options, value)
+		if key == roblox.SettingDailyScreenTimeLimit {
+			var n int
+			valid = valid && json.Unmarshal(value, &n) == nil && n >= 0 && n <= NoLimit
+		}
+		if !valid {
+			writeErrors(w, http.StatusBadRequest, "Invalid setting value")
+			return
+		}
+		if !permitted {
+			writeErrors(w, http.StatusForbidden, "Insufficient permissions to update setting")
+			return
+		}
+	}
+
+	for key, value := range req {
+		if key == roblox.SettingDailyScreenTimeLimit {
+			json.Unmarshal(value, &s.limit)
+			continue
+		}
+		s.settings[key].value = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cascadingSettingUpdates": map[string]any{}})
+}
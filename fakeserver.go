@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/astrostl/blockblox/fakeroblox"
+)
+
+// fakeServerFlags are the flags of fake-server.
+type fakeServerFlags struct {
+	addr          string
+	limit, played int
+}
+
+func defineFakeServerFlags(fs *flag.FlagSet) *fakeServerFlags {
+	f := &fakeServerFlags{}
+	fs.StringVar(&f.addr, "addr", "127.0.0.1:8080", "`address` to listen on")
+	fs.IntVar(&f.limit, "limit", fakeroblox.NoLimit, "starting daily limit in `minutes`")
+	fs.IntVar(&f.played, "played", 0, "`minutes` already played today")
+	return f
+}
+
+// runFakeServer serves a fake Roblox backend until interrupted.
+func runFakeServer(ctx context.Context, stdout io.Writer, opts *fakeServerFlags) error {
+	server := fakeroblox.New(fakeroblox.WithLimit(opts.limit))
+	server.Play(opts.played)
+
+	ln, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return err
+	}
+	url := "http://" + ln.Addr().String()
+	creds := server.Credentials()
+
+	fmt.Fprintf(stdout, "Fake Roblox server listening on %s\n", url)
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Point blockblox at it with:")
+	fmt.Fprintf(stdout, "  export BLOCKBLOX_API_URL=%s\n", url)
+	fmt.Fprintf(stdout, "  export ROBLOX_SECURITY='%s'\n", creds.Security)
+	fmt.Fprintf(stdout, "  export ROBLOX_BROWSER_TRACKER='%s'\n", creds.BrowserTracker)
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Control it with:")
+	fmt.Fprintf(stdout, "  curl %s/_fake/state\n", url)
+	fmt.Fprintf(stdout, "  curl -X POST '%s/_fake/play?minutes=30'\n", url)
+	fmt.Fprintf(stdout, "  curl -X POST '%s/_fake/clock?advance=1h'\n", url)
+	fmt.Fprintf(stdout, "  curl -X POST '%s/_fake/ban?duration=72h&message=Harassment'\n", url)
+
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Firefox cookie extraction. Firefox stores cookie values in plaintext, so
+// unlike Chrome no key is needed.
+
+// firefoxProfile is one profile listed in profiles.ini.
+type firefoxProfile struct {
+	name string
+	path string // absolute
+}
+
+// firefoxDirs returns the directories that may hold Firefox's
+// profiles.ini, most likely first.
+func firefoxDirs() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "Application Support", "Firefox")}, nil
+	case "windows":
+		return []string{filepath.Join(os.Getenv("APPDATA"), "Mozilla", "Firefox")}, nil
+	default:
+		config := os.Getenv("XDG_CONFIG_HOME")
+		if config == "" {
+			config = filepath.Join(home, ".config")
+		}
+		return []string{
+			filepath.Join(home, ".mozilla", "firefox"),
+			filepath.Join(config, "mozilla", "firefox"),
+			filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox"),
+			filepath.Join(home, ".var", "app", "org.mozilla.firefox", ".mozilla", "firefox"),
+		}, nil
+	}
+}
+
+// readFirefoxProfiles parses dir/profiles.ini. Profiles are returned in the
+// order Firefox would pick them: the default of each installation, then the
+// profile marked Default=1, then the rest.
+func readFirefoxProfiles(dir string) ([]firefoxProfile, error) {
+	file, err := os.Open(filepath.Join(dir, "profiles.ini"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type section struct {
+		name   string
+		values map[string]string
+	}
+	var sections []*section
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, &section{name: line[1 : len(line)-1], values: map[string]string{}})
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || len(sections) == 0 {
+			continue
+		}
+		sections[len(sections)-1].values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	resolve := func(path, isRelative string) string {
+		path = filepath.FromSlash(path)
+		if isRelative == "1" {
+			return filepath.Join(dir, path)
+		}
+		return path
+	}
+
+	// Install sections name their default profile by relative path.
+	installDefaults := map[string]bool{}
+	for _, s := range sections {
+		if strings.HasPrefix(s.name, "Install") && s.values["Default"] != "" {
+			installDefaults[resolve(s.values["Default"], "1")] = true
+		}
+	}
+
+	var first, second, rest []firefoxProfile
+	for _, s := range sections {
+		if !strings.HasPrefix(s.name, "Profile") || s.values["Path"] == "" {
+			continue
+		}
+		p := firefoxProfile{name: s.values["Name"], path: resolve(s.values["Path"], s.values["IsRelative"])}
+		switch {
+		case installDefaults[p.path]:
+			first = append(first, p)
+		case s.values["Default"] == "1":
+			second = append(second, p)
+		default:
+			rest = append(rest, p)
+		}
+	}
+	return append(append(first, second...), rest...), nil
+}
+
+// findFirefoxProfiles returns every profile with a cookie database, in
+// Firefox's own order.
+func findFirefoxProfiles() ([]firefoxProfile, error) {
+	dirs, err := firefoxDirs()
+	if err != nil {
+		return nil, err
+	}
+	var profiles []firefoxProfile
+	for _, dir := range dirs {
+		found, err := readFirefoxProfiles(dir)
+		if err != nil {
+			continue
+		}
+		for _, p := range found {
+			if _, err := os.Stat(filepath.Join(p.path, "cookies.sqlite")); err == nil {
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("Firefox profiles not found in %s", strings.Join(dirs, " or "))
+	}
+	return profiles, nil
+}
+
+// readFirefoxCookies reads the Roblox cookies from one profile, failing
+// unless both are there.
+func readFirefoxCookies(profile firefoxProfile) (security string, browserTracker string, err error) {
+	security, browserTracker, err = firefoxCookies(profile)
+	if err != nil {
+		return "", "", err
+	}
+	if security == "" {
+		return "", "", fmt.Errorf(".ROBLOSECURITY cookie not found - make sure you're logged into Roblox in Firefox")
+	}
+	if browserTracker == "" {
+		return "", "", fmt.Errorf("RBXEventTrackerV2 cookie not found")
+	}
+	return security, browserTracker, nil
+}
+
+// firefoxCookies returns whichever Roblox cookies one profile has, "" for
+// any it lacks. When the same cookie is stored more than once (e.g. in a
+// container tab), the most recently used copy wins.
+func firefoxCookies(profile firefoxProfile) (security string, browserTracker string, err error) {
+	// Copy cookies file to temp location (Firefox locks the original)
+	tmpPath, cleanup, err := copyCookieDatabase(filepath.Join(profile.path, "cookies.sqlite"))
+	if err != nil {
+		return "", "", err
+	}
+	defer cleanup()
+
+	db, err := sql.Open("sqlite3", tmpPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT name, value
+		FROM moz_cookies
+		WHERE host LIKE '%roblox.com'
+		AND name IN ('.ROBLOSECURITY', 'RBXEventTrackerV2')
+		ORDER BY lastAccessed DESC
+	`)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return "", "", fmt.Errorf("reading cookies from %s: %w", profile.path, err)
+		}
+		switch {
+		case name == ".ROBLOSECURITY" && security == "":
+			security = value
+		case name == "RBXEventTrackerV2" && browserTracker == "":
+			browserTracker = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", fmt.Errorf("reading cookies from %s: %w", profile.path, err)
+	}
+	return security, browserTracker, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFirefoxCookieDB creates a Firefox cookie database in dir holding
+// rows of name, value and host. A nil value is stored as NULL.
+func writeFirefoxCookieDB(t *testing.T, dir string, rows [][]any) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "cookies.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, name TEXT, value TEXT, host TEXT, lastAccessed INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		if _, err := db.Exec(`INSERT INTO moz_cookies (name, value, host, lastAccessed) VALUES (?, ?, ?, ?)`, append(row, i)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadFirefoxCookies(t *testing.T) {
+	const security = "_|WARNING:-DO-NOT-SHARE-THIS.|_SECRET"
+	const tracker = "CreateDate=1/1/2025 12:00:00 PM&rbxid=&browserid=42"
+
+	t.Run("most recent copy wins", func(t *testing.T) {
+		profile := firefoxProfile{name: "default", path: t.TempDir()}
+		writeFirefoxCookieDB(t, profile.path, [][]any{
+			{".ROBLOSECURITY", "stale", ".roblox.com"},
+			{"RBXEventTrackerV2", tracker, ".roblox.com"},
+			{".ROBLOSECURITY", security, ".roblox.com"},
+			{"theme", "dark", "www.example.com"},
+		})
+		gotSecurity, gotTracker, err := readFirefoxCookies(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotSecurity != security || gotTracker != tracker {
+			t.Errorf("got %q, %q, want %q, %q", gotSecurity, gotTracker, security, tracker)
+		}
+	})
+
+	t.Run("security cookie only", func(t *testing.T) {
+		profile := firefoxProfile{name: "default", path: t.TempDir()}
+		writeFirefoxCookieDB(t, profile.path, [][]any{
+			{".ROBLOSECURITY", security, ".roblox.com"},
+		})
+		_, _, err := readFirefoxCookies(profile)
+		if err == nil || !strings.Contains(err.Error(), "RBXEventTrackerV2") {
+			t.Errorf("err = %v, want the missing RBXEventTrackerV2 cookie reported", err)
+		}
+	})
+
+	t.Run("unreadable row", func(t *testing.T) {
+		profile := firefoxProfile{name: "default", path: t.TempDir()}
+		writeFirefoxCookieDB(t, profile.path, [][]any{
+			{".ROBLOSECURITY", nil, ".roblox.com"},
+			{"RBXEventTrackerV2", tracker, ".roblox.com"},
+		})
+		_, _, err := readFirefoxCookies(profile)
+		if err == nil || !strings.Contains(err.Error(), profile.path) {
+			t.Errorf("err = %v, want an error naming %s", err, profile.path)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeNow is the clock used for relative times, replaced in tests.
+var timeNow = time.Now
+
+func formatTimeUntil(isoDate string) string {
+	endTime, err := time.Parse(time.RFC3339, isoDate)
+	if err != nil {
+		return isoDate
+	}
+	duration := endTime.Sub(timeNow())
+	if duration < 0 {
+		return "expired"
+	}
+	days := int(duration.Hours()) / 24
+	hours := int(duration.Hours()) % 24
+	mins := int(duration.Minutes()) % 60
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%d day(s)", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d hour(s)", hours))
+	}
+	if mins > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d minute(s)", mins))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatResetTime(isoDate string) string {
+	t, err := time.Parse(time.RFC3339, isoDate)
+	if err != nil {
+		return isoDate
+	}
+	local := t.Local()
+	now := timeNow().Local()
+
+	// Check if it's today or tomorrow
+	if local.YearDay() == now.YearDay() && local.Year() == now.Year() {
+		return local.Format("today at 3:04 PM")
+	}
+	tomorrow := now.AddDate(0, 0, 1)
+	if local.YearDay() == tomorrow.YearDay() && local.Year() == tomorrow.Year() {
+		return local.Format("tomorrow at 3:04 PM")
+	}
+	return local.Format("Mon Jan 2 at 3:04 PM")
+}
+
+// formatDuration formats a screen time limit, where 0 and 1440 both mean no
+// limit.
+func formatDuration(minutes int) string {
+	if minutes == 0 || minutes >= 1440 {
+		return "No limit"
+	}
+	return formatMinutes(minutes)
+}
+
+// formatMinutes formats an amount of time such as consumption or time
+// remaining.
+func formatMinutes(minutes int) string {
+	hours := minutes / 60
+	mins := minutes % 60
+	if hours > 0 && mins > 0 {
+		return fmt.Sprintf("%d hour(s) %d minute(s)", hours, mins)
+	} else if hours > 0 {
+		return fmt.Sprintf("%d hour(s)", hours)
+	}
+	return fmt.Sprintf("%d minute(s)", mins)
+}
+
+// maxDuration is the longest duration accepted: Roblox's largest limit,
+// which means no limit.
+const maxDuration = 1440
+
+// durationExamples is shown with durations that cannot be parsed.
+const durationExamples = "90, 90m, 1.5h, 1h 30m, 1:30, none"
+
+// noLimitWords are the durations that mean no limit, read as 0.
+var noLimitWords = map[string]bool{"none": true, "unlimited": true, "off": true}
+
+var (
+	clockDuration = regexp.MustCompile(`^(\d+):([0-5]\d)$`)
+	unitDuration  = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?|\.\d+) *(?:h|hrs?|hours?))? *(?:(\d+(?:\.\d+)?|\.\d+) *(?:m|mins?|minutes?))?$`)
+	plainDuration = regexp.MustCompile(`^(?:\d+(?:\.\d+)?|\.\d+)$`)
+)
+
+// parseDuration parses a duration in minutes: 90, 90m, 90min, 4h, 1.5h,
+// 2 hours, 4h15m, 1h 30m or 1:30. none, unlimited and off are 0, which set
+// takes as no limit. Durations must come to whole minutes and at most
+// maxDuration.
+func parseDuration(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if noLimitWords[s] {
+		return 0, nil
+	}
+
+	var hours, mins string
+	if m := clockDuration.FindStringSubmatch(s); m != nil {
+		hours, mins = m[1], m[2]
+	} else if plainDuration.MatchString(s) {
+		mins = s
+	} else if m := unitDuration.FindStringSubmatch(s); m != nil && (m[1] != "" || m[2] != "") {
+		hours, mins = m[1], m[2]
+	} else {
+		return 0, fmt.Errorf("invalid duration format: %s (use: %s)", s, durationExamples)
+	}
+
+	var total float64
+	for _, part := range []struct {
+		value string
+		scale float64
+	}{{hours, 60}, {mins, 1}} {
+		if part.value == "" {
+			continue
+		}
+		// Digits alone cannot fail to parse, but enough of them reach
+		// infinity, which is too long like any other huge value.
+		n, _ := strconv.ParseFloat(part.value, 64)
+		total += n * part.scale
+	}
+	if total > maxDuration {
+		return 0, fmt.Errorf("duration too long: %s (at most 24h, which means no limit)", s)
+	}
+	minutes := math.Round(total)
+	if math.Abs(total-minutes) > 1e-9 {
+		return 0, fmt.Errorf("duration is not a whole number of minutes: %s", s)
+	}
+	return int(minutes), nil
+}
+
+// limitChange is the limit set was asked for: minutes outright, or, when
+// relative, minutes more or less than the current limit or, with
+// fromConsumed, than today's consumption.
+type limitChange struct {
+	minutes      int
+	relative     bool
+	fromConsumed bool
+}
+
+// parseLimitChange parses set's argument: a duration (90m), a change to the
+// current limit (+30m, -1h), or a limit relative to today's consumption
+// (=consumed, =consumed+15m, =consumed-15m).
+func parseLimitChange(s string) (limitChange, error) {
+	arg := strings.ToLower(strings.TrimSpace(s))
+	var change limitChange
+	if rest, ok := strings.CutPrefix(arg, "=consumed"); ok {
+		change.relative, change.fromConsumed = true, true
+		if rest == "" {
+			return change, nil
+		}
+		if rest[0] != '+' && rest[0] != '-' {
+			return change, fmt.Errorf("invalid limit: %s (use: =consumed, =consumed+15m, =consumed-15m)", s)
+		}
+		arg = rest
+	}
+
+	sign := 1
+	if rest, ok := strings.CutPrefix(arg, "+"); ok {
+		change.relative, arg = true, rest
+	} else if rest, ok := strings.CutPrefix(arg, "-"); ok {
+		change.relative, arg, sign = true, rest, -1
+	}
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		return change, fmt.Errorf("invalid duration format: %s (use: %s)", s, durationExamples)
+	}
+	if change.relative && noLimitWords[strings.TrimSpace(arg)] {
+		return change, fmt.Errorf("invalid limit: %s (no limit cannot be added or subtracted)", s)
+	}
+	minutes, err := parseDuration(arg)
+	if err != nil {
+		return change, err
+	}
+	change.minutes = sign * minutes
+	return change, nil
+}
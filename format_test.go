@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "90", want: 90},
+		{in: "90m", want: 90},
+		{in: "90min", want: 90},
+		{in: "90 minutes", want: 90},
+		{in: "4h", want: 240},
+		{in: "2 hours", want: 120},
+		{in: "1 hr", want: 60},
+		{in: "4h15m", want: 255},
+		{in: "1h 30m", want: 90},
+		{in: "1 hour 30 minutes", want: 90},
+		{in: "1.5h", want: 90},
+		{in: ".25h", want: 15},
+		{in: "1:30", want: 90},
+		{in: "0:05", want: 5},
+		{in: " 4H ", want: 240},
+		{in: "0", want: 0},
+		{in: "none", want: 0},
+		{in: "Unlimited", want: 0},
+		{in: "off", want: 0},
+		{in: "24h", want: 1440},
+		{in: "24:00", want: 1440},
+
+		{in: "", wantErr: true},
+		{in: "soon", wantErr: true},
+		{in: "h", wantErr: true},
+		{in: "-5", wantErr: true},
+		{in: "+5", wantErr: true},
+		{in: "30m 1h", wantErr: true},
+		{in: "1:60", wantErr: true},
+		{in: "1:5", wantErr: true},
+		{in: "1.5", wantErr: true},
+		{in: "0.01h", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1441", wantErr: true},
+		{in: "24:01", wantErr: true},
+		{in: "25h", wantErr: true},
+		{in: "99999999999999999999h", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDuration(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// FuzzParseDuration checks that anything accepted is a valid limit, and that
+// the same number of minutes reads back the same in the other forms.
+func FuzzParseDuration(f *testing.F) {
+	for _, s := range []string{"90", "90m", "1.5h", "1h 30m", "2 hours", "1:30", "none", "24h", "1441", "-5", "1e3"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		minutes, err := parseDuration(s)
+		if err != nil {
+			return
+		}
+		if minutes < 0 || minutes > maxDuration {
+			t.Fatalf("parseDuration(%q) = %d, outside 0..%d", s, minutes, maxDuration)
+		}
+		for _, form := range []string{
+			fmt.Sprint(minutes),
+			fmt.Sprintf("%dm", minutes),
+			fmt.Sprintf("%dh%dm", minutes/60, minutes%60),
+			fmt.Sprintf("%d:%02d", minutes/60, minutes%60),
+		} {
+			if got, err := parseDuration(form); err != nil || got != minutes {
+				t.Errorf("parseDuration(%q) = %d, %v; %q was %d", form, got, err, s, minutes)
+			}
+		}
+	})
+}
+
+func FuzzParseLimitChange(f *testing.F) {
+	for _, s := range []string{"90m", "+30m", "-1h", "=consumed", "=consumed+15m", "=consumed-1:30", "+-5", "+off"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		change, err := parseLimitChange(s)
+		if err != nil {
+			return
+		}
+		if change.minutes < -maxDuration || change.minutes > maxDuration {
+			t.Fatalf("parseLimitChange(%q) changes by %d minutes, beyond %d", s, change.minutes, maxDuration)
+		}
+		if !change.relative && change.minutes < 0 {
+			t.Fatalf("parseLimitChange(%q) = %d, a negative limit", s, change.minutes)
+		}
+	})
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		minutes int
+		limit   string // formatDuration, for limits
+		amount  string // formatMinutes, for consumption and time left
+	}{
+		{0, "No limit", "0 minute(s)"},
+		{45, "45 minute(s)", "45 minute(s)"},
+		{60, "1 hour(s)", "1 hour(s)"},
+		{90, "1 hour(s) 30 minute(s)", "1 hour(s) 30 minute(s)"},
+		{1439, "23 hour(s) 59 minute(s)", "23 hour(s) 59 minute(s)"},
+		{1440, "No limit", "24 hour(s)"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.minutes); got != tt.limit {
+			t.Errorf("formatDuration(%d) = %q, want %q", tt.minutes, got, tt.limit)
+		}
+		if got := formatMinutes(tt.minutes); got != tt.amount {
+			t.Errorf("formatMinutes(%d) = %q, want %q", tt.minutes, got, tt.amount)
+		}
+	}
+}
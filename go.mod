@@ -5,4 +5,7 @@ go 1.25.5
 require (
 	github.com/mattn/go-sqlite3 v1.14.32
 	golang.org/x/crypto v0.46.0
+	golang.org/x/term v0.38.0
 )
+
+require golang.org/x/sys v0.39.0 // indirect
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// loginFlags are the flags of login.
+type loginFlags struct {
+	cookies, har, store string
+}
+
+func defineLoginFlags(fs *flag.FlagSet) *loginFlags {
+	f := &loginFlags{}
+	fs.StringVar(&f.cookies, "cookies", "", "Netscape cookies.txt `file` to read the cookies from")
+	fs.StringVar(&f.har, "har", "", "HAR `file` saved from the browser's DevTools Network tab")
+	fs.StringVar(&f.store, "store", "", "where to save credentials: file, secret-service or env (default: secret-service when available, else file)")
+	return f
+}
+
+// runLogin saves credentials for account from cookies given by hand: typed
+// at a hidden prompt, piped in, or read from a cookies.txt or HAR export.
+// They are only saved once Roblox accepts them; verbose logs the requests
+// checking them.
+func runLogin(ctx context.Context, w, stderr io.Writer, cfg configDir, account string, verbose bool, opts *loginFlags, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("unexpected argument %q (cookies are read from standard input, --cookies or --har)", args[0])
+	}
+	if opts.store != "" {
+		if _, err := newCredentialStore(cfg, account, opts.store, stderr); err != nil {
+			return err
+		}
+	}
+
+	var creds roblox.Credentials
+	var source string
+	var err error
+	switch {
+	case opts.cookies != "" && opts.har != "":
+		return usageErrorf("use only one of --cookies and --har")
+	case opts.cookies != "":
+		source = opts.cookies
+		creds, err = readNetscapeCookies(opts.cookies)
+	case opts.har != "":
+		source = opts.har
+		creds, err = readHARCookies(opts.har)
+	case isInteractive():
+		source = "what was entered"
+		creds, err = promptCookies(stderr)
+	default:
+		source = "standard input"
+		creds, err = parsePastedCookies(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	if creds.Security == "" {
+		msg := fmt.Sprintf("no .ROBLOSECURITY cookie found in %s", source)
+		if opts.har != "" {
+			msg += " (export it with \"Export HAR (with sensitive data)\"; the default export leaves cookies out)"
+		}
+		return errors.New(msg)
+	}
+	if creds.BrowserTracker == "" {
+		return fmt.Errorf("no RBXEventTrackerV2 cookie found in %s", source)
+	}
+
+	client, err := newClient(stderr, account, verbose, roblox.StaticCredentials(creds))
+	if err != nil {
+		return err
+	}
+	user, err := client.GetUser(ctx)
+	if errors.Is(err, roblox.ErrUnauthorized) {
+		return withCode(exitAuth, errors.New("Roblox did not accept these cookies; they may have expired, or belong to a session that was logged out"))
+	} else if err != nil {
+		return withCode(exitCode(err), fmt.Errorf("checking the cookies with Roblox: %s", describeError(err)))
+	}
+	fmt.Fprintf(w, "Signed in as %s (@%s)\n", user.DisplayName, user.Name)
+
+	// Roblox may have rotated the cookies while checking them.
+	if err := saveCredentials(w, stderr, cfg, account, client.Credentials(), opts.store); err != nil {
+		return err
+	}
+	// There is no browser profile to extract fresh ones from later.
+	if err := rememberBrowser(cfg, account, "", ""); err != nil {
+		return err
+	}
+	rememberUser(cfg, account, user)
+	return nil
+}
+
+// promptCookies asks for both cookies without echoing them.
+func promptCookies(w io.Writer) (roblox.Credentials, error) {
+	fmt.Fprintln(w, "Paste each cookie's value from your browser's DevTools (Application > Cookies > https://www.roblox.com).")
+	security, err := readSecret(w, ".ROBLOSECURITY: ")
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	tracker, err := readSecret(w, "RBXEventTrackerV2: ")
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	return roblox.Credentials{Security: strings.TrimSpace(security), BrowserTracker: strings.TrimSpace(tracker)}, nil
+}
+
+// setCookie fills in the credential called name, by its cookie or
+// environment variable name. Other names are ignored.
+func setCookie(creds *roblox.Credentials, name, value string) {
+	switch name {
+	case ".ROBLOSECURITY", "ROBLOX_SECURITY":
+		creds.Security = value
+	case "RBXEventTrackerV2", "ROBLOX_BROWSER_TRACKER":
+		creds.BrowserTracker = value
+	}
+}
+
+// isRobloxDomain reports whether a cookie for domain is sent to roblox.com.
+func isRobloxDomain(domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	return domain == "roblox.com" || strings.HasSuffix(domain, ".roblox.com")
+}
+
+// parsePastedCookies reads name=value pairs, one per line as in
+// ~/.blockblox.env or separated by semicolons as in a Cookie header.
+func parsePastedCookies(r io.Reader) (roblox.Credentials, error) {
+	var creds roblox.Credentials
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) >= 7 && strings.EqualFold(line[:7], "cookie:") {
+			line = line[7:]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, pair := range strings.Split(line, ";") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			setCookie(&creds, strings.TrimSpace(name), value)
+		}
+	}
+	return creds, scanner.Err()
+}
+
+// readNetscapeCookies reads the Roblox cookies from a cookies.txt export:
+// tab-separated domain, subdomains flag, path, secure flag, expiry, name
+// and value. HttpOnly cookies such as .ROBLOSECURITY are written with a
+// "#HttpOnly_" prefix on the domain, so they look like comments.
+func readNetscapeCookies(path string) (roblox.Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	defer file.Close()
+
+	var creds roblox.Credentials
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimRight(scanner.Text(), "\r"), "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 || !isRobloxDomain(fields[0]) {
+			continue
+		}
+		setCookie(&creds, fields[5], fields[6])
+	}
+	if err := scanner.Err(); err != nil {
+		return roblox.Credentials{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// har is the part of an HTTP Archive that holds cookies.
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				URL     string      `json:"url"`
+				Cookies []harCookie `json:"cookies"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+			} `json:"request"`
+			Response struct {
+				Cookies []harCookie `json:"cookies"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// readHARCookies reads the Roblox cookies from requests to roblox.com in a
+// HAR file. Entries are in the order they were made, so cookies Roblox
+// rotated with Set-Cookie replace the ones sent before.
+func readHARCookies(path string) (roblox.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return roblox.Credentials{}, err
+	}
+	var archive har
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return roblox.Credentials{}, fmt.Errorf("reading %s: not a HAR file: %w", path, err)
+	}
+
+	var creds roblox.Credentials
+	for _, entry := range archive.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || !isRobloxDomain(u.Hostname()) {
+			continue
+		}
+		for _, c := range entry.Request.Cookies {
+			setCookie(&creds, c.Name, c.Value)
+		}
+		if len(entry.Request.Cookies) == 0 {
+			for _, h := range entry.Request.Headers {
+				if strings.EqualFold(h.Name, "cookie") {
+					pasted, _ := parsePastedCookies(strings.NewReader(h.Value))
+					if pasted.Security != "" {
+						creds.Security = pasted.Security
+					}
+					if pasted.BrowserTracker != "" {
+						creds.BrowserTracker = pasted.BrowserTracker
+					}
+				}
+			}
+		}
+		for _, c := range entry.Response.Cookies {
+			if c.Value != "" {
+				setCookie(&creds, c.Name, c.Value)
+			}
+		}
+	}
+	return creds, nil
+}
@@ -0,0 +1,773 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astrostl/blockblox/fakeroblox"
+	"github.com/astrostl/blockblox/roblox"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// The fake account lives in a fixed zone at a fixed time, so reset times and
+// ban countdowns render the same everywhere.
+var (
+	testLoc = time.FixedZone("CST", -6*60*60)
+	testNow = time.Date(2025, 12, 14, 15, 30, 0, 0, testLoc)
+)
+
+func TestMain(m *testing.M) {
+	time.Local = testLoc
+	isInteractive = func() bool { return false }
+	secretServiceAvailable = func() bool { return false }
+	os.Exit(m.Run())
+}
+
+// scenario is one CLI invocation against a fresh fake Roblox backend.
+type scenario struct {
+	name  string
+	args  []string
+	setup func(s *fakeroblox.Server)
+	env   map[string]string
+	home  func(t *testing.T, s *fakeroblox.Server) // populates the temporary $HOME
+	stdin string
+}
+
+var scenarios = []scenario{
+	// README examples
+	{name: "get_no_limit", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.Play(150)
+	}},
+	{name: "get_with_limit", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(240)
+		s.Play(150)
+	}},
+	{name: "get_temporary_time_active", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(1)
+		s.Play(150)
+		s.AddTemporaryTime(30)
+	}},
+	{name: "get_screen_time_blocked", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(90)
+	}},
+	{name: "temp_add", args: []string{"temp", "15"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(90)
+	}},
+
+	// get
+	{name: "get_under_an_hour", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(45)
+		s.Play(20)
+	}},
+	{name: "get_exactly_at_limit_with_temp", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(60)
+		s.AddTemporaryTime(10)
+	}},
+	{name: "get_banned", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.Ban("Ban 3 Days", "Harassment", 72*time.Hour)
+	}},
+	{name: "get_session_expired", args: []string{"get"}, setup: func(s *fakeroblox.Server) {
+		s.ExpireSession()
+	}},
+	{name: "get_missing_credentials", args: []string{"get"}, env: map[string]string{"ROBLOX_SECURITY": ""}},
+
+	// set
+	{name: "set_minutes", args: []string{"set", "90"}, setup: func(s *fakeroblox.Server) {
+		s.Play(30)
+	}},
+	{name: "set_hours_and_minutes", args: []string{"set", "4h15m"}, setup: func(s *fakeroblox.Server) {
+		s.Play(150)
+	}},
+	{name: "set_under_an_hour", args: []string{"set", "45m"}},
+	{name: "set_no_limit", args: []string{"set", "0"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(30)
+	}},
+	{name: "set_below_consumption", args: []string{"set", "1h"}, setup: func(s *fakeroblox.Server) {
+		s.Play(150)
+	}},
+	{name: "set_screen_time_blocked", args: []string{"set", "2h"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(90)
+	}},
+	{name: "set_banned", args: []string{"set", "2h"}, setup: func(s *fakeroblox.Server) {
+		s.Ban("Ban 1 Day", "Spam", 24*time.Hour)
+	}},
+	{name: "set_fractional_hours", args: []string{"set", "1.5h"}},
+	{name: "set_spelled_out", args: []string{"set", "2 hours"}},
+	{name: "set_clock", args: []string{"set", "1:30"}},
+	{name: "set_unlimited", args: []string{"set", "unlimited"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+	}},
+	{name: "set_too_long", args: []string{"set", "25h"}},
+	{name: "set_add_no_limit_keyword", args: []string{"set", "+off"}},
+	{name: "set_missing_argument", args: []string{"set"}},
+	{name: "set_invalid_duration", args: []string{"set", "soon"}},
+	{name: "set_add", args: []string{"set", "+30m"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(30)
+	}},
+	{name: "set_subtract", args: []string{"set", "-1h"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(150)
+		s.Play(30)
+	}},
+	{name: "set_subtract_clamped", args: []string{"set", "-5"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(3)
+	}},
+	{name: "set_add_clamped", args: []string{"set", "+2h"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(23 * 60)
+	}},
+	{name: "set_add_no_limit", args: []string{"set", "+30m"}},
+	{name: "set_consumed_plus", args: []string{"set", "=consumed+15m"}, setup: func(s *fakeroblox.Server) {
+		s.Play(100)
+	}},
+	{name: "set_consumed_invalid", args: []string{"set", "=consumed15m"}},
+	{name: "set_double_sign", args: []string{"set", "+-5"}},
+
+	// temp
+	{name: "temp_not_blocked", args: []string{"temp", "15m"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(30)
+	}},
+	{name: "temp_no_limit_keyword", args: []string{"temp", "none"}},
+	{name: "temp_banned", args: []string{"temp", "5"}, setup: func(s *fakeroblox.Server) {
+		s.Ban("Ban 3 Days", "Harassment", 72*time.Hour)
+	}},
+	{name: "temp_zero", args: []string{"temp", "0"}},
+	{name: "temp_missing_argument", args: []string{"temp"}},
+	{name: "temp_rate_limited", args: []string{"temp", "5"}, setup: func(s *fakeroblox.Server) {
+		s.ExhaustRateLimit(fakeroblox.BucketTempScreenTime)
+	}},
+	{name: "get_unreachable", args: []string{"get"}, env: map[string]string{"BLOCKBLOX_API_URL": "http://127.0.0.1:1"}},
+
+	// --output
+	{name: "get_json", args: []string{"--output", "json", "get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(45)
+	}},
+	{name: "get_yaml", args: []string{"--output", "yaml", "get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(45)
+	}},
+	{name: "get_json_no_limit", args: []string{"--output", "json", "get"}},
+	{name: "get_json_screen_time_blocked", args: []string{"--output", "json", "get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(60)
+	}},
+	{name: "get_yaml_banned", args: []string{"--output", "yaml", "get"}, setup: func(s *fakeroblox.Server) {
+		s.Ban("Ban 3 Days", "Harassment", 72*time.Hour)
+	}},
+	{name: "get_json_session_expired", args: []string{"--output", "json", "get"}, setup: func(s *fakeroblox.Server) {
+		s.ExpireSession()
+	}},
+	{name: "get_json_missing_credentials", args: []string{"--output", "json", "get"}, env: map[string]string{"ROBLOX_SECURITY": ""}},
+	{name: "set_json", args: []string{"--output", "json", "set", "90"}, setup: func(s *fakeroblox.Server) {
+		s.Play(100)
+	}},
+	{name: "set_json_relative", args: []string{"--output", "json", "set", "=consumed-10m"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(40)
+	}},
+	{name: "set_json_invalid_duration", args: []string{"--output", "json", "set", "soon"}},
+	{name: "temp_json", args: []string{"--output", "json", "temp", "15"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(60)
+	}},
+	{name: "temp_yaml_rate_limited", args: []string{"--output", "yaml", "temp", "5"}, setup: func(s *fakeroblox.Server) {
+		s.ExhaustRateLimit(fakeroblox.BucketTempScreenTime)
+	}},
+	{name: "week_json_unsupported", args: []string{"--output", "json", "week"}},
+	{name: "get_unknown_output", args: []string{"--output", "xml", "get"}},
+
+	// init
+	{name: "init_firefox", args: []string{"init", "--browser", "firefox"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(45)
+	}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeFirefoxProfile(t, s.Credentials())
+	}},
+	{name: "init_firefox_not_logged_in", args: []string{"init", "--browser", "firefox"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeFirefoxProfile(t, roblox.Credentials{})
+	}},
+	{name: "init_several_profiles", args: []string{"init"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeChromiumProfile(t, "chrome", "Default", "Person 1", roblox.Credentials{})
+		writeChromiumProfile(t, "brave", "Default", "Personal", s.Credentials())
+		writeChromiumProfile(t, "brave", "Profile 2", "Work", s.Credentials())
+	}},
+	{name: "init_brave_profile", args: []string{"init", "--browser", "brave", "--profile", "Profile 2"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeChromiumProfile(t, "brave", "Default", "Personal", s.Credentials())
+		writeChromiumProfile(t, "brave", "Profile 2", "Work", s.Credentials())
+	}},
+	{name: "init_profile_by_name", args: []string{"init", "--browser", "brave", "--profile", "work"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeChromiumProfile(t, "brave", "Default", "Personal", roblox.Credentials{})
+		writeChromiumProfile(t, "brave", "Profile 2", "Work", s.Credentials())
+	}},
+	{name: "init_profile_not_logged_in", args: []string{"init", "--browser", "brave", "--profile", "Personal"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeChromiumProfile(t, "brave", "Default", "Personal", roblox.Credentials{})
+		writeChromiumProfile(t, "brave", "Profile 2", "Work", s.Credentials())
+	}},
+	{name: "init_unknown_browser", args: []string{"init", "--browser", "netscape"}},
+	{name: "init_encrypted", args: []string{"init", "--browser", "firefox"}, env: map[string]string{"BLOCKBLOX_PASSPHRASE": "hunter2"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeFirefoxProfile(t, s.Credentials())
+		saveTestCredentials(t, defaultAccount, storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "init_unknown_store", args: []string{"init", "--browser", "firefox", "--store", "floppy"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeFirefoxProfile(t, s.Credentials())
+	}},
+
+	// saved credentials
+	{name: "get_encrypted_credentials", args: []string{"get"}, env: noEnvCredentials("hunter2"), setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "get_encrypted_no_passphrase", args: []string{"get"}, env: noEnvCredentials(""), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "get_encrypted_wrong_passphrase", args: []string{"get"}, env: noEnvCredentials("swordfish"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "get_legacy_env_file", args: []string{"get"}, env: noEnvCredentials(""), setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeEnvFile, s.Credentials(), "")
+	}},
+	// expired sessions
+	{name: "get_session_expired_saved", args: []string{"get"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveExtractedCredentials(t, s.Credentials())
+		s.ExpireSession()
+	}},
+	{name: "get_session_expired_given_by_hand", args: []string{"--profile", "alex", "get"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeFile, s.Credentials(), "hunter2")
+		s.ExpireSession()
+	}},
+	{name: "get_session_expired_reauth", args: []string{"get"}, env: autoReauth(noEnvCredentials("hunter2")), setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveExtractedCredentials(t, s.Credentials())
+		writeFirefoxProfile(t, s.SignIn())
+	}},
+	{name: "get_session_expired_reauth_same_session", args: []string{"get"}, env: autoReauth(noEnvCredentials("hunter2")), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveExtractedCredentials(t, s.Credentials())
+		writeFirefoxProfile(t, s.Credentials())
+		s.ExpireSession()
+	}},
+	{name: "get_session_expired_reauth_other_user", args: []string{"get"}, env: autoReauth(noEnvCredentials("hunter2")), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveExtractedCredentials(t, s.Credentials())
+		config, err := loadAccounts("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.info(defaultAccount).UserID, config.info(defaultAccount).Username = 42, "SomeoneElse"
+		if err := config.save(); err != nil {
+			t.Fatal(err)
+		}
+		writeFirefoxProfile(t, s.SignIn())
+	}},
+	{name: "credentials_migrate", args: []string{"credentials", "migrate"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "credentials_migrate_nothing_saved", args: []string{"credentials", "migrate"}, env: noEnvCredentials("hunter2")},
+	{name: "credentials_migrate_to_env", args: []string{"credentials", "migrate", "--to", "env"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "credentials_missing_subcommand", args: []string{"credentials"}},
+
+	// login
+	{name: "login_cookie_header", args: []string{"login"}, env: noEnvCredentials("hunter2"), stdin: "Cookie: " + cookieHeader(fakeroblox.New().Credentials()) + "\n"},
+	{name: "login_env_lines", args: []string{"login", "--store", "env"}, env: noEnvCredentials(""), stdin: "ROBLOX_SECURITY=" + fakeroblox.New().Credentials().Security + "\nROBLOX_BROWSER_TRACKER=\"" + fakeroblox.New().Credentials().BrowserTracker + "\"\n"},
+	{name: "login_cookies_txt", args: []string{"--profile", "alex", "login", "--cookies", "$HOME/cookies.txt"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		writeCookiesTxt(t, s.Credentials())
+	}},
+	{name: "login_har", args: []string{"login", "--har", "$HOME/roblox.har"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		writeHAR(t, s.Credentials())
+	}},
+	{name: "login_har_sanitized", args: []string{"login", "--har", "$HOME/roblox.har"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		writeHAR(t, roblox.Credentials{})
+	}},
+	{name: "login_missing_tracker", args: []string{"login"}, env: noEnvCredentials("hunter2"), stdin: ".ROBLOSECURITY=_|WARNING:-abc\n"},
+	{name: "login_rejected", args: []string{"login"}, env: noEnvCredentials("hunter2"), setup: func(s *fakeroblox.Server) {
+		s.ExpireSession()
+	}, stdin: cookieHeader(fakeroblox.New().Credentials()) + "\n"},
+	{name: "login_missing_file", args: []string{"login", "--cookies", "$HOME/missing.txt"}, env: noEnvCredentials("hunter2")},
+
+	// doctor
+	{name: "doctor", args: []string{"doctor"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}},
+	{name: "doctor_saved_credentials", args: []string{"doctor"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "doctor_missing_credentials", args: []string{"doctor"}, env: noEnvCredentials("")},
+	{name: "doctor_wrong_passphrase", args: []string{"doctor"}, env: noEnvCredentials("swordfish"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "doctor_cookie_format", args: []string{"doctor"}, env: map[string]string{"ROBLOX_SECURITY": "ABCDEF", "ROBLOX_BROWSER_TRACKER": "CreateDate=1/1/2025"}},
+	{name: "doctor_session_expired", args: []string{"doctor"}, setup: func(s *fakeroblox.Server) {
+		s.ExpireSession()
+	}},
+	{name: "doctor_screen_time_blocked", args: []string{"doctor"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(90)
+	}},
+	{name: "doctor_banned", args: []string{"doctor"}, setup: func(s *fakeroblox.Server) {
+		s.Ban("Ban 3 Days", "Harassment", 72*time.Hour)
+	}},
+	{name: "doctor_rate_limited", args: []string{"doctor"}, setup: func(s *fakeroblox.Server) {
+		s.ExhaustRateLimit(fakeroblox.BucketUserSettings)
+	}},
+	{name: "doctor_file_permissions", args: []string{"doctor"}, env: noEnvCredentials(""), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeEnvFile, s.Credentials(), "")
+		home, _ := os.UserHomeDir()
+		if err := os.Chmod(filepath.Join(home, ".blockblox.env"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}},
+
+	// accounts
+	{name: "profile_get", args: []string{"--profile", "alex", "get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "profile_from_env", args: []string{"get"}, env: map[string]string{"BLOCKBLOX_PROFILE": "alex"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "profile_missing_credentials", args: []string{"--profile", "sam", "get"}},
+	{name: "profile_invalid_name", args: []string{"--profile", "../etc", "get"}},
+	{name: "accounts_add", args: []string{"accounts", "add", "alex", "--browser", "firefox"}, env: map[string]string{"BLOCKBLOX_PASSPHRASE": "hunter2"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		writeFirefoxProfile(t, s.Credentials())
+	}},
+	{name: "accounts_add_existing", args: []string{"accounts", "add", "alex"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "accounts_add_missing_name", args: []string{"accounts", "add"}},
+	{name: "accounts_list", args: []string{"accounts", "list"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeEnvFile, s.Credentials(), "")
+		saveTestCredentials(t, "alex", storeFile, s.Credentials(), "hunter2")
+		saveTestCredentials(t, "sam", storeEnvFile, s.Credentials(), "")
+		config := &accountConfig{Default: "alex", Accounts: map[string]*accountInfo{
+			"alex": {Store: storeFile, UserID: 1, Username: "CoolPlayer123", DisplayName: "Alex"},
+			"sam":  {Store: storeEnvFile},
+		}}
+		if err := config.save(); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "accounts_list_empty", args: []string{"accounts", "list"}},
+	{name: "accounts_rename", args: []string{"accounts", "rename", "alex", "sam"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "accounts_rename_unknown", args: []string{"accounts", "rename", "alex", "sam"}},
+	{name: "accounts_remove", args: []string{"accounts", "remove", "alex"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "accounts_default", args: []string{"accounts", "default", "alex"}, home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, "alex", storeEnvFile, s.Credentials(), "")
+	}},
+	{name: "accounts_missing_subcommand", args: []string{"accounts"}},
+
+	// week
+	{name: "week", args: []string{"week"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		for daysAgo, minutes := range []int{45, 150, 0, 95, 120, 30, 200} {
+			s.SetPlayed(daysAgo, minutes)
+		}
+	}},
+	{name: "week_over_today", args: []string{"week"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.SetPlayed(0, 75)
+		s.SetPlayed(1, 90)
+	}},
+	{name: "week_no_limit", args: []string{"week"}, setup: func(s *fakeroblox.Server) {
+		s.SetPlayed(1, 30)
+		s.Play(20)
+	}},
+	{name: "week_screen_time_blocked", args: []string{"week"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(60)
+		s.Play(90)
+	}},
+
+	// settings
+	{name: "settings_list", args: []string{"settings", "list"}},
+	{name: "settings_get", args: []string{"settings", "get", "whoCanChatWithMeInApp"}},
+	{name: "settings_get_read_only", args: []string{"settings", "get", "contentAgeRestriction"}},
+	{name: "settings_set", args: []string{"settings", "set", "whoCanChatWithMeInApp", "NoOne"}},
+	{name: "settings_set_boolean", args: []string{"settings", "set", "allowEnableGroupNotifications", "false"}},
+	{name: "settings_set_invalid_value", args: []string{"settings", "set", "whoCanChatWithMeInApp", "Everyone"}},
+	{name: "settings_set_read_only", args: []string{"settings", "set", "contentAgeRestriction", "ThirteenPlus"}},
+	{name: "settings_get_unknown", args: []string{"settings", "get", "favoriteColor"}},
+	{name: "settings_missing_subcommand", args: []string{"settings"}},
+	{name: "settings_unknown_subcommand", args: []string{"settings", "reset"}},
+
+	// misc
+	{name: "version", args: []string{"--version"}},
+	{name: "help", args: []string{"help"}},
+	{name: "no_arguments", args: nil},
+	{name: "unknown_command", args: []string{"frobnicate"}},
+
+	// commands and global flags
+	{name: "set_help", args: []string{"set", "--help"}},
+	{name: "help_accounts", args: []string{"help", "accounts"}},
+	{name: "help_unknown_command", args: []string{"help", "frobnicate"}},
+	{name: "get_unknown_flag", args: []string{"get", "--frobnicate"}},
+	{name: "get_flags_after_command", args: []string{"get", "--output", "yaml"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+		s.Play(45)
+	}},
+	{name: "login_profile_after_command", args: []string{"login", "--profile", "alex"}, env: noEnvCredentials("hunter2"), stdin: "Cookie: " + cookieHeader(fakeroblox.New().Credentials()) + "\n"},
+	{name: "login_config_dir", args: []string{"--config", "$HOME/elsewhere", "login"}, env: noEnvCredentials("hunter2"), stdin: "Cookie: " + cookieHeader(fakeroblox.New().Credentials()) + "\n"},
+	{name: "get_config_dir_elsewhere", args: []string{"--config", "$HOME/elsewhere", "get"}, env: noEnvCredentials("hunter2"), home: func(t *testing.T, s *fakeroblox.Server) {
+		saveTestCredentials(t, defaultAccount, storeFile, s.Credentials(), "hunter2")
+	}},
+	{name: "get_verbose", args: []string{"--verbose", "get"}, setup: func(s *fakeroblox.Server) {
+		s.SetLimit(120)
+	}},
+	{name: "completion_missing_shell", args: []string{"completion"}},
+	{name: "completion_unknown_shell", args: []string{"completion", "tcsh"}},
+}
+
+func TestCLIGolden(t *testing.T) {
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			got := runScenario(t, sc)
+			path := filepath.Join("testdata", "golden", sc.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("output mismatch for %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// TestRotatedCookiesAreSaved has the fake server rotate .ROBLOSECURITY and
+// checks the new cookie is written back, since the old one stops working.
+func TestRotatedCookiesAreSaved(t *testing.T) {
+	for _, storeName := range []string{storeFile, storeEnvFile} {
+		t.Run(storeName, func(t *testing.T) {
+			server := fakeroblox.New(fakeroblox.WithClock(fakeroblox.FixedClock(testNow)))
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+			t.Setenv("BLOCKBLOX_API_URL", ts.URL)
+			for k, v := range noEnvCredentials("hunter2") {
+				t.Setenv(k, v)
+			}
+			saveTestCredentials(t, defaultAccount, storeName, server.Credentials(), "hunter2")
+			server.RotateSession()
+
+			for range 2 {
+				var stdout, stderr bytes.Buffer
+				if code := run(context.Background(), []string{"get"}, &stdout, &stderr); code != 0 {
+					t.Fatalf("blockblox get: exit status %d\nstderr: %s", code, stderr.String())
+				}
+			}
+
+			store, err := newCredentialStore("", defaultAccount, storeName, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := server.Credentials(); saved != want {
+				t.Errorf("saved credentials = %+v, want the rotated %+v", saved, want)
+			}
+		})
+	}
+}
+
+// runScenario runs the CLI in-process against a fresh fake server and
+// renders its exit status, stdout and stderr in golden file form.
+func runScenario(t *testing.T, sc scenario) string {
+	t.Helper()
+
+	server := fakeroblox.New(
+		fakeroblox.WithClock(fakeroblox.FixedClock(testNow)),
+		fakeroblox.WithLocation(testLoc),
+	)
+	if sc.setup != nil {
+		sc.setup(server)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	prevNow := timeNow
+	timeNow = server.Clock().Now
+	defer func() { timeNow = prevNow }()
+
+	// Keep the user's real credentials and caches out of the test.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+
+	creds := server.Credentials()
+	t.Setenv("BLOCKBLOX_API_URL", ts.URL)
+	t.Setenv("ROBLOX_SECURITY", creds.Security)
+	t.Setenv("ROBLOX_BROWSER_TRACKER", creds.BrowserTracker)
+	for k, v := range sc.env {
+		t.Setenv(k, v)
+	}
+	if sc.home != nil {
+		sc.home(t, server)
+	}
+
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString(sc.stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	prevStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = prevStdin }()
+
+	// Arguments may name files in the temporary $HOME.
+	args := make([]string, len(sc.args))
+	for i, arg := range sc.args {
+		args[i] = strings.ReplaceAll(arg, "$HOME", home)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", strings.TrimSpace("$ blockblox "+strings.Join(sc.args, " ")))
+	fmt.Fprintf(&b, "exit status: %d\n", code)
+	fmt.Fprintf(&b, "-- stdout --\n%s", stdout.String())
+	fmt.Fprintf(&b, "-- stderr --\n%s", stderr.String())
+	out := strings.ReplaceAll(b.String(), home, "$HOME")
+	return strings.ReplaceAll(out, ts.URL, "$BLOCKBLOX_API_URL")
+}
+
+// noEnvCredentials clears the credential environment variables so saved
+// credentials are used, and sets BLOCKBLOX_PASSPHRASE unless it is empty.
+func noEnvCredentials(passphrase string) map[string]string {
+	env := map[string]string{"ROBLOX_SECURITY": "", "ROBLOX_BROWSER_TRACKER": ""}
+	if passphrase != "" {
+		env[passphraseEnv] = passphrase
+	}
+	return env
+}
+
+// autoReauth adds BLOCKBLOX_AUTO_REAUTH to env.
+func autoReauth(env map[string]string) map[string]string {
+	env[autoReauthEnv] = "1"
+	return env
+}
+
+// saveExtractedCredentials saves the default account's creds encrypted with
+// "hunter2", as init would after extracting them from the Firefox profile
+// written by writeFirefoxProfile.
+func saveExtractedCredentials(t *testing.T, creds roblox.Credentials) {
+	t.Helper()
+	saveTestCredentials(t, defaultAccount, storeFile, creds, "hunter2")
+	if err := rememberBrowser("", defaultAccount, "firefox", "abcd1234.default-release"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// saveTestCredentials saves account's creds to the named store under $HOME,
+// as an earlier init would have, encrypting with passphrase where needed.
+func saveTestCredentials(t *testing.T, account, name string, creds roblox.Credentials, passphrase string) {
+	t.Helper()
+	prev, had := os.LookupEnv(passphraseEnv)
+	os.Setenv(passphraseEnv, passphrase)
+	defer func() {
+		if had {
+			os.Setenv(passphraseEnv, prev)
+		} else {
+			os.Unsetenv(passphraseEnv)
+		}
+	}()
+
+	store, err := newCredentialStore("", account, name, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(creds); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cookieHeader formats creds as a browser's Cookie request header.
+func cookieHeader(creds roblox.Credentials) string {
+	return fmt.Sprintf("RBXSource=rbx_acquisition_time=1; .ROBLOSECURITY=%s; RBXEventTrackerV2=%s", creds.Security, creds.BrowserTracker)
+}
+
+// writeCookiesTxt exports creds to $HOME/cookies.txt in Netscape format, as
+// browser extensions do, with .ROBLOSECURITY marked HttpOnly.
+func writeCookiesTxt(t *testing.T, creds roblox.Credentials) {
+	t.Helper()
+	content := "# Netscape HTTP Cookie File\n" +
+		".example.com\tTRUE\t/\tFALSE\t1893456000\t.ROBLOSECURITY\tnot-roblox\n" +
+		"#HttpOnly_.roblox.com\tTRUE\t/\tTRUE\t1893456000\t.ROBLOSECURITY\t" + creds.Security + "\n" +
+		".roblox.com\tTRUE\t/\tFALSE\t1893456000\tRBXEventTrackerV2\t" + creds.BrowserTracker + "\n"
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "cookies.txt"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeHAR saves $HOME/roblox.har with a request to roblox.com carrying
+// creds, as DevTools exports it. Empty creds leave the cookies out, as the
+// default sanitized export does.
+func writeHAR(t *testing.T, creds roblox.Credentials) {
+	t.Helper()
+	type cookie struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	var cookies []cookie
+	if creds.Security != "" {
+		cookies = []cookie{{".ROBLOSECURITY", creds.Security}, {"RBXEventTrackerV2", creds.BrowserTracker}}
+	}
+	entry := map[string]any{
+		"request":  map[string]any{"method": "GET", "url": "https://users.roblox.com/v1/users/authenticated", "cookies": cookies, "headers": []any{}},
+		"response": map[string]any{"status": 200, "cookies": []any{}},
+	}
+	other := map[string]any{
+		"request":  map[string]any{"method": "GET", "url": "https://www.example.com/", "cookies": []cookie{{".ROBLOSECURITY", "not-roblox"}}},
+		"response": map[string]any{"status": 200},
+	}
+	data, err := json.Marshal(map[string]any{"log": map[string]any{"version": "1.2", "entries": []any{entry, other}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "roblox.har"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeChromiumProfile creates profile dir of a Chrome-family browser under
+// $HOME, named name in Local State and holding creds, or no Roblox cookies
+// if creds is empty. Values are left unencrypted in the value column, as
+// older Chrome versions stored them, so no safe storage password is needed.
+// Profiles other than Default keep their cookies in Network/, as current
+// Chrome does.
+func writeChromiumProfile(t *testing.T, browserID, dir, name string, creds roblox.Credentials) {
+	t.Helper()
+	browsers, err := chromiumBrowsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userDataDir string
+	for _, b := range browsers {
+		if b.id == browserID {
+			userDataDir = b.userDataDir
+		}
+	}
+	cookiesDir := filepath.Join(userDataDir, dir)
+	if dir != "Default" {
+		cookiesDir = filepath.Join(cookiesDir, "Network")
+	}
+	if err := os.MkdirAll(cookiesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	localStatePath := filepath.Join(userDataDir, "Local State")
+	localState := map[string]map[string]map[string]map[string]string{}
+	if data, err := os.ReadFile(localStatePath); err == nil {
+		if err := json.Unmarshal(data, &localState); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if localState["profile"] == nil {
+		localState["profile"] = map[string]map[string]map[string]string{"info_cache": {}}
+	}
+	localState["profile"]["info_cache"][dir] = map[string]string{"name": name}
+	data, err := json.Marshal(localState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(localStatePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := []fixtureCookie{{host: "www.example.com", name: "theme", value: "dark"}}
+	if creds.Security != "" {
+		cookies = append(cookies,
+			fixtureCookie{host: ".roblox.com", name: ".ROBLOSECURITY", value: creds.Security},
+			fixtureCookie{host: ".roblox.com", name: "RBXEventTrackerV2", value: creds.BrowserTracker},
+		)
+	}
+	writeChromeCookieDB(t, filepath.Join(cookiesDir, "Cookies"), chromeHostDigestVersion, cookies)
+}
+
+// writeFirefoxProfile creates a default Firefox profile under $HOME holding
+// creds, or no Roblox cookies at all if creds is empty.
+func writeFirefoxProfile(t *testing.T, creds roblox.Credentials) {
+	t.Helper()
+	dirs, err := firefoxDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := dirs[0]
+	profile := filepath.Join(dir, "Profiles", "abcd1234.default-release")
+	if err := os.MkdirAll(profile, 0700); err != nil {
+		t.Fatal(err)
+	}
+	ini := "[Profile0]\nName=default-release\nIsRelative=1\nPath=Profiles/abcd1234.default-release\n\n" +
+		"[Install4F96D1932A9F858E]\nDefault=Profiles/abcd1234.default-release\nLocked=1\n"
+	if err := os.WriteFile(filepath.Join(dir, "profiles.ini"), []byte(ini), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(profile, "cookies.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, name TEXT, value TEXT, host TEXT, lastAccessed INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	cookies := [][]any{{"theme", "dark", "www.example.com"}}
+	if creds.Security != "" {
+		cookies = append(cookies,
+			[]any{".ROBLOSECURITY", creds.Security, ".roblox.com"},
+			[]any{"RBXEventTrackerV2", creds.BrowserTracker, ".roblox.com"},
+		)
+	}
+	for _, c := range cookies {
+		if _, err := db.Exec(`INSERT INTO moz_cookies (name, value, host, lastAccessed) VALUES (?, ?, ?, 0)`, c...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
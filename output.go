@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// Output formats for --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// reportVersion is the version of the --output json|yaml schema. Fields may
+// be added within a version; renaming, removing or changing the meaning of
+// one bumps it.
+const reportVersion = 1
+
+// report is what get, set and temp found, written instead of their text
+// with --output json or yaml. Values that were not learned, because the
+// command failed first or does not look them up, are null.
+type report struct {
+	Version int         `json:"version"`
+	Command string      `json:"command"`
+	User    *reportUser `json:"user"`
+
+	// LimitMinutes is the daily limit, null when there is none
+	// (Unlimited) or it is not known.
+	LimitMinutes     *int `json:"limitMinutes"`
+	Unlimited        bool `json:"unlimited"`
+	ConsumedMinutes  *int `json:"consumedMinutes"`
+	RemainingMinutes *int `json:"remainingMinutes"`
+	// OverLimitMinutes is how far past the limit today's play is, which
+	// only temporary time allows.
+	OverLimitMinutes    int  `json:"overLimitMinutes"`
+	TemporaryTimeActive bool `json:"temporaryTimeActive"`
+	// AddedMinutes is the temporary time temp granted.
+	AddedMinutes *int `json:"addedMinutes,omitempty"`
+	// PreviousLimit is the limit a relative set changed.
+	PreviousLimit *reportLimit `json:"previousLimit,omitempty"`
+
+	Restriction *reportRestriction `json:"restriction"`
+	Ban         *reportBan         `json:"ban"`
+	Error       *reportError       `json:"error"`
+}
+
+type reportUser struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type reportLimit struct {
+	LimitMinutes *int `json:"limitMinutes"` // null when unlimited
+	Unlimited    bool `json:"unlimited"`
+}
+
+type reportRestriction struct {
+	Source     string `json:"source"` // "ban", "screenTime" or "other"
+	SourceCode int    `json:"sourceCode"`
+	StartTime  string `json:"startTime"`
+	EndTime    string `json:"endTime"`
+}
+
+type reportBan struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	EndTime string `json:"endTime"`
+}
+
+type reportError struct {
+	// Kind is one of usage, auth, rate_limited, banned, blocked,
+	// moderated, network, api, interrupted or error.
+	Kind              string `json:"kind"`
+	Message           string `json:"message"`
+	Hint              string `json:"hint,omitempty"` // what to do about it
+	ExitCode          int    `json:"exitCode"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+func newReport(command string) *report {
+	return &report{Version: reportVersion, Command: command}
+}
+
+func (r *report) setUser(user *roblox.UserResponse) {
+	r.User = &reportUser{ID: user.ID, Name: user.Name, DisplayName: user.DisplayName}
+}
+
+// setUsage fills in the limit and what is left of it. A limit of 1440
+// minutes or more is no limit.
+func (r *report) setUsage(limit, consumed int) {
+	r.ConsumedMinutes = &consumed
+	if limit <= 0 || limit >= 1440 {
+		r.LimitMinutes, r.RemainingMinutes, r.Unlimited = nil, nil, true
+		return
+	}
+	remaining := max(limit-consumed, 0)
+	r.LimitMinutes, r.RemainingMinutes, r.Unlimited = &limit, &remaining, false
+	r.OverLimitMinutes = max(consumed-limit, 0)
+	r.TemporaryTimeActive = consumed > limit
+}
+
+// setPreviousLimit records the limit set changed from.
+func (r *report) setPreviousLimit(limit int) {
+	if limit <= 0 || limit >= 1440 {
+		r.PreviousLimit = &reportLimit{Unlimited: true}
+		return
+	}
+	r.PreviousLimit = &reportLimit{LimitMinutes: &limit}
+}
+
+func (r *report) setRestriction(restriction *roblox.Restriction) {
+	source := "other"
+	switch restriction.Source {
+	case roblox.RestrictionSourceBan:
+		source = "ban"
+	case roblox.RestrictionSourceScreenTime:
+		source = "screenTime"
+	}
+	r.Restriction = &reportRestriction{Source: source, SourceCode: restriction.Source, StartTime: restriction.StartTime, EndTime: restriction.EndTime}
+}
+
+func (r *report) setBan(ban *roblox.BanDetails) {
+	r.Ban = &reportBan{Type: ban.PunishmentTypeDescription, Message: ban.MessageToUser, EndTime: ban.EndDate}
+}
+
+// setError records why the command failed. Only the first failure is
+// kept. Any lines after the first of message are advice, kept as the hint.
+func (r *report) setError(kind, message string, exitCode int) {
+	if r.Error == nil {
+		message, hint, _ := strings.Cut(message, "\n")
+		r.Error = &reportError{Kind: kind, Message: message, Hint: hint, ExitCode: exitCode}
+	}
+}
+
+// errorKind classifies a client error for reports.
+func errorKind(err error) string {
+	var apiErr *roblox.APIError
+	switch {
+	case errors.Is(err, roblox.ErrUnauthorized):
+		return "auth"
+	case errors.Is(err, roblox.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, roblox.ErrModerated):
+		return "moderated"
+	case errors.As(err, &apiErr), errors.Is(err, roblox.ErrUnexpectedSchema):
+		return "api"
+	case unreachable(err):
+		return "network"
+	default:
+		return "error"
+	}
+}
+
+// validateOutput checks an --output value.
+func validateOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (use text, json or yaml)", format)
+}
+
+// writeReport writes r as JSON or YAML.
+func writeReport(w io.Writer, format string, r *report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if format == outputYAML {
+		return jsonToYAML(w, data)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// jsonToYAML rewrites a JSON document as block-style YAML, keeping the
+// order of object keys. Strings stay double-quoted, which YAML reads the
+// same way as JSON, so no value can be mistaken for a number or boolean.
+func jsonToYAML(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := readOrdered(dec)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	switch v := value.(type) {
+	case []yamlField:
+		writeYAMLFields(&b, v, 0, false)
+	case []any:
+		writeYAMLItems(&b, v, 0)
+	default:
+		b.WriteString(yamlScalar(v) + "\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// yamlField is one key of a JSON object, in document order.
+type yamlField struct {
+	key   string
+	value any
+}
+
+// readOrdered decodes the next JSON value, with objects as []yamlField
+// and arrays as []any.
+func readOrdered(dec *json.Decoder) (any, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		fields := []yamlField{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key.(string), value})
+		}
+		_, err := dec.Token()
+		return fields, err
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			item, err := readOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+	return t, nil
+}
+
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeYAMLFields writes an object's fields at indent. firstInline means
+// the first is written after a list item's "- ".
+func writeYAMLFields(b *strings.Builder, fields []yamlField, indent int, firstInline bool) {
+	pad := strings.Repeat("  ", indent)
+	for i, f := range fields {
+		if i > 0 || !firstInline {
+			b.WriteString(pad)
+		}
+		key := f.key
+		if !plainYAMLKey.MatchString(key) {
+			key = yamlScalar(key)
+		}
+		switch v := f.value.(type) {
+		case []yamlField:
+			if len(v) == 0 {
+				b.WriteString(key + ": {}\n")
+				continue
+			}
+			b.WriteString(key + ":\n")
+			writeYAMLFields(b, v, indent+1, false)
+		case []any:
+			if len(v) == 0 {
+				b.WriteString(key + ": []\n")
+				continue
+			}
+			b.WriteString(key + ":\n")
+			writeYAMLItems(b, v, indent+1)
+		default:
+			b.WriteString(key + ": " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+// writeYAMLItems writes an array's items at indent.
+func writeYAMLItems(b *strings.Builder, items []any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, item := range items {
+		b.WriteString(pad + "-")
+		switch v := item.(type) {
+		case []yamlField:
+			if len(v) == 0 {
+				b.WriteString(" {}\n")
+				continue
+			}
+			b.WriteString(" ")
+			writeYAMLFields(b, v, indent+1, true)
+		case []any:
+			if len(v) == 0 {
+				b.WriteString(" []\n")
+				continue
+			}
+			b.WriteString("\n")
+			writeYAMLItems(b, v, indent+1)
+		default:
+			b.WriteString(" " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+// yamlScalar writes a JSON scalar as YAML.
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return v.String()
+	case string:
+		quoted, _ := json.Marshal(v)
+		return string(quoted)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestJSONToYAML covers the shapes reports do not use yet, so adding a
+// list or map to the schema later renders as expected.
+func TestJSONToYAML(t *testing.T) {
+	tests := []struct {
+		name, json, want string
+	}{
+		{
+			name: "scalars",
+			json: `{"a": 1, "b": "yes", "c": true, "d": null, "e": 1.5}`,
+			want: "a: 1\nb: \"yes\"\nc: true\nd: null\ne: 1.5\n",
+		},
+		{
+			name: "nested",
+			json: `{"user": {"id": 1, "tags": {"x": "y"}}}`,
+			want: "user:\n  id: 1\n  tags:\n    x: \"y\"\n",
+		},
+		{
+			name: "lists",
+			json: `{"days": [{"date": "2025-12-14", "minutes": 30}, {"date": "2025-12-15", "minutes": 0}], "ids": [1, 2]}`,
+			want: "days:\n  - date: \"2025-12-14\"\n    minutes: 30\n  - date: \"2025-12-15\"\n    minutes: 0\nids:\n  - 1\n  - 2\n",
+		},
+		{
+			name: "empty",
+			json: `{"list": [], "map": {}, "nested": [[], {}]}`,
+			want: "list: []\nmap: {}\nnested:\n  - []\n  - {}\n",
+		},
+		{
+			name: "awkward keys and strings",
+			json: `{"has space": "line\nbreak", "x": "#not a comment", "y": "null"}`,
+			want: "\"has space\": \"line\\nbreak\"\nx: \"#not a comment\"\ny: \"null\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := jsonToYAML(&b, []byte(tt.json)); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
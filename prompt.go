@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// readSecret prints prompt to w and reads a line from the terminal without
+// echoing it.
+func readSecret(w io.Writer, prompt string) (string, error) {
+	fmt.Fprint(w, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(w)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,400 @@
+// Package roblox is a client for the undocumented Roblox APIs used to read and
+// change a teen account's screen time settings.
+//
+// The client is configured with functional options so it can be embedded in
+// other tools and pointed at a local fake server in tests:
+//
+//	client, err := roblox.NewClient(
+//		roblox.WithEndpoints(roblox.SingleHost("http://127.0.0.1:8080")),
+//		roblox.WithCredentials(roblox.StaticCredentials{Security: "...", BrowserTracker: "..."}),
+//	)
+package roblox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	csrfTokenHeader = "X-Csrf-Token"
+
+	// DefaultTimeout bounds each API call when the caller's context has no
+	// earlier deadline.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Endpoints holds the base URL of every Roblox host the client talks to.
+// Paths are appended to these, so a single fake server can stand in for all
+// of them (see SingleHost).
+type Endpoints struct {
+	APIs           string // apis.roblox.com: user settings and parental controls
+	Users          string // users.roblox.com
+	UserModeration string // usermoderation.roblox.com
+	Web            string // www.roblox.com
+}
+
+// DefaultEndpoints are the production Roblox hosts.
+var DefaultEndpoints = Endpoints{
+	APIs:           "https://apis.roblox.com",
+	Users:          "https://users.roblox.com",
+	UserModeration: "https://usermoderation.roblox.com",
+	Web:            "https://www.roblox.com",
+}
+
+// SingleHost returns Endpoints that route every host to base.
+func SingleHost(base string) Endpoints {
+	base = strings.TrimRight(base, "/")
+	return Endpoints{APIs: base, Users: base, UserModeration: base, Web: base}
+}
+
+func (e Endpoints) settingsURL() string {
+	return e.APIs + "/user-settings-api/v1/user-settings/settings-and-options"
+}
+
+func (e Endpoints) updateURL() string {
+	return e.APIs + "/user-settings-api/v1/user-settings"
+}
+
+func (e Endpoints) weeklyScreentimeURL() string {
+	return e.APIs + "/parental-controls-api/v1/parental-controls/get-weekly-screentime"
+}
+
+func (e Endpoints) tempScreenTimeURL() string {
+	return e.APIs + "/parental-controls-api/v1/parental-controls/add-temporary-screentime"
+}
+
+func (e Endpoints) authenticatedUserURL() string {
+	return e.Users + "/v1/users/authenticated"
+}
+
+func (e Endpoints) userByIDURL(userID int64) string {
+	return fmt.Sprintf("%s/v1/users/%d", e.Users, userID)
+}
+
+func (e Endpoints) restrictionURL() string {
+	return e.UserModeration + "/v2/not-approved"
+}
+
+func (e Endpoints) banDetailsURL() string {
+	return e.UserModeration + "/v1/not-approved"
+}
+
+func (e Endpoints) notApprovedPageURL() string {
+	return e.Web + "/not-approved"
+}
+
+// Client talks to the Roblox APIs on behalf of a single signed-in account.
+type Client struct {
+	httpClient  *http.Client
+	endpoints   Endpoints
+	credentials CredentialsSource
+	creds       Credentials
+	jar         http.CookieJar
+	onRotate    func(Credentials)
+	timeout     time.Duration
+	tokenCache  TokenCache
+	now         func() time.Time
+
+	limiter         *limiter
+	rateLimitPolicy RateLimitPolicy
+	retryPolicy     RetryPolicy
+
+	mu        sync.Mutex // guards creds, csrfToken and clockSkew
+	csrfToken string
+	clockSkew *time.Duration // local clock minus Roblox's, from the last Date header
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithEndpoints overrides the Roblox hosts the client talks to.
+func WithEndpoints(e Endpoints) Option {
+	return func(c *Client) {
+		c.endpoints = e
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for every request.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout sets the default per-call timeout. Zero disables it, leaving
+// only the caller's context to bound a call.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithCSRFTokenCache persists the CSRF token between clients. See
+// FileTokenCache.
+func WithCSRFTokenCache(tc TokenCache) Option {
+	return func(c *Client) {
+		c.tokenCache = tc
+	}
+}
+
+// WithClock sets the clock used to turn Roblox's relative days into
+// calendar dates. The default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithCredentials sets where the session cookies come from. The default is
+// EnvCredentials.
+func WithCredentials(src CredentialsSource) Option {
+	return func(c *Client) {
+		c.credentials = src
+	}
+}
+
+// NewClient creates a Client. Credentials are resolved once, up front, so a
+// missing cookie is reported here rather than on the first request.
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient:  &http.Client{},
+		endpoints:   DefaultEndpoints,
+		credentials: EnvCredentials(),
+		timeout:     DefaultTimeout,
+		now:         time.Now,
+
+		limiter:         newLimiter(DefaultRateLimits),
+		rateLimitPolicy: DefaultRateLimitPolicy,
+		retryPolicy:     DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	creds, err := c.credentials.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	c.creds = creds
+	if err := c.seedJar(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// withTimeout bounds ctx by the client's default timeout. An earlier
+// deadline already on ctx still wins.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// GetScreenTime returns the daily screen time limit in minutes.
+func (c *Client) GetScreenTime(ctx context.Context) (int, error) {
+	settings, err := c.GetSettings(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	setting, ok := settings[SettingDailyScreenTimeLimit]
+	if !ok {
+		return 0, &SchemaError{URL: c.endpoints.settingsURL(), Err: fmt.Errorf("%s missing", SettingDailyScreenTimeLimit)}
+	}
+	var minutes int
+	if err := json.Unmarshal(setting.CurrentValue, &minutes); err != nil {
+		return 0, &SchemaError{URL: c.endpoints.settingsURL(), Err: fmt.Errorf("%s: %w", SettingDailyScreenTimeLimit, err)}
+	}
+
+	return minutes, nil
+}
+
+// GetUser returns the signed-in user. When the account is moderated the
+// authenticated endpoint refuses to answer, so the user is recovered from the
+// ban details or the not-approved page instead.
+func (c *Client) GetUser(ctx context.Context) (*UserResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: c.endpoints.authenticatedUserURL()})
+	if err != nil {
+		// Check if user is moderated - try to get user info via ban details or HTML scrape
+		if errors.Is(err, ErrModerated) {
+			// Try ban details first (has user ID for bans)
+			if ban, banErr := c.GetBanDetails(ctx); banErr == nil && ban.PunishedUserId > 0 {
+				return c.GetUserByID(ctx, ban.PunishedUserId)
+			}
+			// Fall back to HTML scrape (works for screen time blocks)
+			if user, htmlErr := c.GetUserFromHTML(ctx); htmlErr == nil {
+				return user, nil
+			}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var user UserResponse
+	if err := decodeJSON(resp, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+var (
+	userIDAttrRe = regexp.MustCompile(`data-userid="(\d+)"`)
+	nameAttrRe   = regexp.MustCompile(`data-name="([^"]+)"`)
+)
+
+// GetUserFromHTML scrapes the signed-in user from the not-approved page,
+// which still renders while the account is screen time blocked.
+func (c *Client) GetUserFromHTML(ctx context.Context) (*UserResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url := c.endpoints.notApprovedPageURL()
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: url})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	html := string(body)
+
+	// Parse data-userid="..." and data-name="..."
+	userIDMatch := userIDAttrRe.FindStringSubmatch(html)
+	nameMatch := nameAttrRe.FindStringSubmatch(html)
+
+	if userIDMatch == nil || nameMatch == nil {
+		return nil, &SchemaError{URL: url, Err: fmt.Errorf("could not parse user info from HTML")}
+	}
+
+	userID, _ := strconv.ParseInt(userIDMatch[1], 10, 64)
+	name := nameMatch[1]
+
+	// Get display name from public API
+	if fullUser, err := c.GetUserByID(ctx, userID); err == nil {
+		return fullUser, nil
+	}
+
+	// Fall back to just what we scraped
+	return &UserResponse{
+		ID:          userID,
+		Name:        name,
+		DisplayName: name,
+	}, nil
+}
+
+// GetUserByID looks up a user through the public users API. No credentials
+// are sent.
+func (c *Client) GetUserByID(ctx context.Context, userID int64) (*UserResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: c.endpoints.userByIDURL(userID), public: true})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var user UserResponse
+	if err := decodeJSON(resp, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetTodayConsumption returns the minutes played today.
+func (c *Client) GetTodayConsumption(ctx context.Context, userID int64) (int, error) {
+	weekly, err := c.GetWeeklyScreentime(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return weekly.Today().MinutesPlayed, nil
+}
+
+// SetScreenTime sets the daily screen time limit in minutes. Roblox treats
+// 1440 as no limit.
+func (c *Client) SetScreenTime(ctx context.Context, minutes int) error {
+	return c.UpdateSettings(ctx, map[string]any{SettingDailyScreenTimeLimit: minutes})
+}
+
+// AddTemporaryScreenTime grants extra minutes for today. It works even while
+// the account is screen time blocked.
+func (c *Client) AddTemporaryScreenTime(ctx context.Context, minutes int) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{
+		method: http.MethodPost,
+		url:    c.endpoints.tempScreenTimeURL(),
+		bucket: bucketTempScreenTime,
+		// Resending after a response was lost could grant the minutes twice.
+		retry: retryUnsent,
+		body:  map[string]int{"minutes": minutes},
+		ok:    []int{http.StatusOK, http.StatusNoContent},
+	})
+	if err != nil {
+		return err
+	}
+	discard(resp)
+
+	return nil
+}
+
+// GetRestriction returns the account's current restriction, or nil when it
+// is not restricted.
+func (c *Client) GetRestriction(ctx context.Context) (*Restriction, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: c.endpoints.restrictionURL()})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result RestrictionResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Restriction, nil
+}
+
+// GetBanDetails returns the details of the account's ban. Screen time blocks
+// are not bans and come back empty.
+func (c *Client) GetBanDetails(ctx context.Context) (*BanDetails, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: c.endpoints.banDetailsURL()})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result BanDetails
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
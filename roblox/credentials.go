@@ -0,0 +1,52 @@
+package roblox
+
+import (
+	"fmt"
+	"os"
+)
+
+// Credentials are the session cookies Roblox expects on every authenticated
+// request.
+type Credentials struct {
+	Security       string // .ROBLOSECURITY
+	BrowserTracker string // RBXEventTrackerV2
+}
+
+// CredentialsSource supplies the session cookies for a Client.
+type CredentialsSource interface {
+	Credentials() (Credentials, error)
+}
+
+// CredentialsFunc adapts a function to a CredentialsSource.
+type CredentialsFunc func() (Credentials, error)
+
+// Credentials calls f.
+func (f CredentialsFunc) Credentials() (Credentials, error) {
+	return f()
+}
+
+// StaticCredentials is a CredentialsSource that always returns itself.
+type StaticCredentials Credentials
+
+// Credentials returns s.
+func (s StaticCredentials) Credentials() (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// EnvCredentials reads the cookies from the ROBLOX_SECURITY and
+// ROBLOX_BROWSER_TRACKER environment variables.
+func EnvCredentials() CredentialsSource {
+	return CredentialsFunc(func() (Credentials, error) {
+		security := os.Getenv("ROBLOX_SECURITY")
+		browserTracker := os.Getenv("ROBLOX_BROWSER_TRACKER")
+
+		if security == "" {
+			return Credentials{}, fmt.Errorf("ROBLOX_SECURITY environment variable not set")
+		}
+		if browserTracker == "" {
+			return Credentials{}, fmt.Errorf("ROBLOX_BROWSER_TRACKER environment variable not set")
+		}
+
+		return Credentials{Security: security, BrowserTracker: browserTracker}, nil
+	})
+}
@@ -0,0 +1,83 @@
+package roblox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// RefreshCSRFToken obtains a fresh CSRF token, replacing any cached one. It
+// sends a settings change without a token, which Roblox refuses before
+// looking at it, so nothing is changed.
+func (c *Client) RefreshCSRFToken(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{
+		method:   http.MethodPost,
+		url:      c.endpoints.updateURL(),
+		bucket:   bucketUserSettings,
+		body:     map[string]any{},
+		csrfOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		discard(resp)
+		return errors.New("roblox accepted a change without a CSRF token")
+	}
+	return nil
+}
+
+// ClockSkew returns how far the local clock is ahead of Roblox's, as of the
+// last response. ok is false until a response with a Date header arrives.
+// Date has a resolution of one second.
+func (c *Client) ClockSkew() (skew time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clockSkew == nil {
+		return 0, false
+	}
+	return *c.clockSkew, true
+}
+
+// RateLimitStatus is the client's view of one rate limit bucket.
+type RateLimitStatus struct {
+	Bucket    string
+	Limit     int
+	Window    time.Duration
+	Remaining int
+	// RetryAfter is how long until the next request may be sent, when
+	// the bucket is empty or blocked after a 429.
+	RetryAfter time.Duration
+}
+
+// RateLimits reports every rate limit bucket the client knows a budget for,
+// by bucket name.
+func (c *Client) RateLimits() []RateLimitStatus {
+	names := map[string]bool{}
+	c.limiter.mu.Lock()
+	for name := range c.limiter.seed {
+		names[name] = true
+	}
+	for name := range c.limiter.buckets {
+		names[name] = true
+	}
+	c.limiter.mu.Unlock()
+
+	now := c.limiter.now()
+	var statuses []RateLimitStatus
+	for name := range names {
+		if b := c.limiter.get(name, false); b != nil {
+			if status := b.status(now); status.Limit > 0 {
+				status.Bucket = name
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Bucket < statuses[j].Bucket })
+	return statuses
+}
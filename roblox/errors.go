@@ -0,0 +1,151 @@
+package roblox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the failure modes callers usually need to tell apart.
+// Match them with errors.Is; use errors.As with *APIError for the status code
+// and Roblox's own error payload.
+var (
+	ErrModerated        = errors.New("user is moderated")
+	ErrUnauthorized     = errors.New("session is unauthorized or expired")
+	ErrCSRFRejected     = errors.New("CSRF token rejected")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrNotFound         = errors.New("not found")
+	ErrUnexpectedSchema = errors.New("unexpected response schema")
+)
+
+// ErrorDetail is one entry of the errors[] array Roblox returns on failure.
+type ErrorDetail struct {
+	Code              int    `json:"code"`
+	Message           string `json:"message"`
+	UserFacingMessage string `json:"userFacingMessage,omitempty"`
+}
+
+// APIError is returned when Roblox answers with an unexpected status code.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Errors     []ErrorDetail // parsed from the body, when present
+	Body       string        // raw body, for payloads that are not JSON
+	RetryAfter time.Duration // set for 429s that say when to come back
+
+	kind error
+}
+
+func (e *APIError) Error() string {
+	detail := strings.TrimSpace(e.Body)
+	if len(e.Errors) > 0 {
+		msgs := make([]string, len(e.Errors))
+		for i, d := range e.Errors {
+			msgs[i] = d.Message
+		}
+		detail = strings.Join(msgs, "; ")
+	}
+	if detail == "" {
+		return fmt.Sprintf("API error: %s", e.Status)
+	}
+	return fmt.Sprintf("API error: %s - %s", e.Status, detail)
+}
+
+// Is reports whether the error belongs to one of the sentinel categories.
+func (e *APIError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// HasMessage reports whether any entry of Errors contains substr.
+func (e *APIError) HasMessage(substr string) bool {
+	for _, d := range e.Errors {
+		if strings.Contains(strings.ToLower(d.Message), strings.ToLower(substr)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError reads resp's body and classifies the failure.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	e := &APIError{
+		Method:     resp.Request.Method,
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(body),
+	}
+
+	var payload struct {
+		Errors []ErrorDetail `json:"errors"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Errors = payload.Errors
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		e.kind = ErrUnauthorized
+	case http.StatusForbidden:
+		switch {
+		case e.HasMessage("moderated"):
+			e.kind = ErrModerated
+		case e.HasMessage("token validation failed") || resp.Header.Get(csrfTokenHeader) != "":
+			e.kind = ErrCSRFRejected
+		}
+	case http.StatusNotFound:
+		e.kind = ErrNotFound
+	case http.StatusTooManyRequests:
+		e.kind = ErrRateLimited
+		e.RetryAfter = retryAfter(resp.Header)
+	}
+
+	return e
+}
+
+// retryAfter reads how long to wait from Retry-After, falling back to
+// Roblox's X-Ratelimit-Reset.
+func retryAfter(h http.Header) time.Duration {
+	for _, name := range []string{"Retry-After", "X-Ratelimit-Reset"} {
+		if secs, err := strconv.Atoi(strings.TrimSpace(h.Get(name))); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 0
+}
+
+// SchemaError is returned when a response cannot be parsed into the shape
+// the client expects, usually because Roblox changed an undocumented API.
+type SchemaError struct {
+	URL string
+	Err error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: %v", e.URL, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrUnexpectedSchema.
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrUnexpectedSchema
+}
+
+// decodeJSON decodes resp's body into v, reporting failures as SchemaError.
+func decodeJSON(resp *http.Response, v any) error {
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &SchemaError{URL: resp.Request.URL.String(), Err: err}
+	}
+	return nil
+}
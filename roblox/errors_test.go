@@ -0,0 +1,114 @@
+package roblox
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		want       error
+		notWant    []error
+		retryAfter time.Duration
+	}{
+		{
+			name:    "moderation",
+			status:  http.StatusForbidden,
+			body:    `{"errors":[{"code":0,"message":"User is moderated"}]}`,
+			want:    ErrModerated,
+			notWant: []error{ErrCSRFRejected},
+		},
+		{
+			name:    "moderation with a token header",
+			status:  http.StatusForbidden,
+			header:  http.Header{"X-Csrf-Token": {"abc"}},
+			body:    `{"errors":[{"code":0,"message":"User is moderated"}]}`,
+			want:    ErrModerated,
+			notWant: []error{ErrCSRFRejected},
+		},
+		{
+			name:    "CSRF",
+			status:  http.StatusForbidden,
+			header:  http.Header{"X-Csrf-Token": {"abc"}},
+			body:    `{"errors":[{"code":0,"message":"Token Validation Failed"}]}`,
+			want:    ErrCSRFRejected,
+			notWant: []error{ErrModerated},
+		},
+		{
+			name:    "CSRF without a body",
+			status:  http.StatusForbidden,
+			header:  http.Header{"X-Csrf-Token": {"abc"}},
+			want:    ErrCSRFRejected,
+			notWant: []error{ErrModerated},
+		},
+		{
+			name:    "CSRF message without a token",
+			status:  http.StatusForbidden,
+			body:    `{"errors":[{"code":0,"message":"XSRF Token Validation Failed"}]}`,
+			want:    ErrCSRFRejected,
+			notWant: []error{ErrModerated},
+		},
+		{
+			name:    "other 403",
+			status:  http.StatusForbidden,
+			body:    `{"errors":[{"code":0,"message":"Unauthorized user"}]}`,
+			notWant: []error{ErrModerated, ErrCSRFRejected},
+		},
+		{
+			name:   "401",
+			status: http.StatusUnauthorized,
+			want:   ErrUnauthorized,
+		},
+		{
+			name:       "429",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"7"}},
+			want:       ErrRateLimited,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:   "404",
+			status: http.StatusNotFound,
+			body:   "not json",
+			want:   ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "https://example.com/x", nil)
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     http.StatusText(tt.status),
+				Header:     tt.header,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+				Request:    req,
+			}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			err := newAPIError(resp)
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("%v is not %v", err, tt.want)
+			}
+			for _, kind := range tt.notWant {
+				if errors.Is(err, kind) {
+					t.Errorf("%v is %v", err, kind)
+				}
+			}
+			if err.RetryAfter != tt.retryAfter {
+				t.Errorf("RetryAfter = %v, want %v", err.RetryAfter, tt.retryAfter)
+			}
+			if err.Body != tt.body {
+				t.Errorf("Body = %q, want %q", err.Body, tt.body)
+			}
+		})
+	}
+}
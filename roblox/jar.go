@@ -0,0 +1,112 @@
+package roblox
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// Session cookie names.
+const (
+	securityCookie       = ".ROBLOSECURITY"
+	browserTrackerCookie = "RBXEventTrackerV2"
+)
+
+// WithCredentialsRotation sets a function called when Roblox replaces a
+// session cookie with Set-Cookie, with the complete updated credentials, so
+// they can be saved before the old ones stop working.
+func WithCredentialsRotation(fn func(Credentials)) Option {
+	return func(c *Client) {
+		c.onRotate = fn
+	}
+}
+
+// Credentials returns the session cookies the client sends, including any
+// Roblox has rotated since the client was created.
+func (c *Client) Credentials() Credentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.creds
+}
+
+// seedJar puts the session cookies in a new jar for every endpoint host.
+// Roblox sets them for the whole roblox.com domain; other hosts, such as a
+// local fake server, get host cookies.
+func (c *Client) seedJar() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	seeded := map[string]bool{}
+	for _, base := range []string{c.endpoints.APIs, c.endpoints.Users, c.endpoints.UserModeration, c.endpoints.Web} {
+		u, err := url.Parse(base)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		domain := ""
+		host := u.Hostname()
+		if host == "roblox.com" || strings.HasSuffix(host, ".roblox.com") {
+			domain = "roblox.com"
+		}
+		key := domain
+		if key == "" {
+			key = u.Host
+		}
+		if seeded[key] {
+			continue
+		}
+		seeded[key] = true
+		jar.SetCookies(u, []*http.Cookie{
+			{Name: securityCookie, Value: c.creds.Security, Domain: domain, Path: "/"},
+			{Name: browserTrackerCookie, Value: c.creds.BrowserTracker, Domain: domain, Path: "/"},
+		})
+	}
+	c.jar = jar
+	return nil
+}
+
+// addCookies sends the jar's cookies for req. The header is written by hand,
+// as net/http would quote the tracker's spaces and Roblox expects them bare.
+func (c *Client) addCookies(req *http.Request) {
+	var pairs []string
+	for _, cookie := range c.jar.Cookies(req.URL) {
+		pairs = append(pairs, cookie.Name+"="+cookie.Value)
+	}
+	if len(pairs) > 0 {
+		req.Header.Set("Cookie", strings.Join(pairs, "; "))
+	}
+}
+
+// updateCookies stores any cookies resp sets and, when that changes the
+// session cookies, reports the new credentials to the rotation handler.
+func (c *Client) updateCookies(resp *http.Response) {
+	cookies := resp.Cookies()
+	if len(cookies) == 0 || resp.Request == nil {
+		return
+	}
+	u := resp.Request.URL
+	c.jar.SetCookies(u, cookies)
+
+	c.mu.Lock()
+	updated := c.creds
+	for _, cookie := range c.jar.Cookies(u) {
+		switch cookie.Name {
+		case securityCookie:
+			updated.Security = cookie.Value
+		case browserTrackerCookie:
+			updated.BrowserTracker = cookie.Value
+		}
+	}
+	// A cookie that was deleted rather than replaced is a logout, which
+	// the next request will report; there is nothing worth saving.
+	rotated := updated != c.creds && updated.Security != "" && updated.BrowserTracker != ""
+	if rotated {
+		c.creds = updated
+	}
+	c.mu.Unlock()
+
+	if rotated && c.onRotate != nil {
+		c.onRotate(updated)
+	}
+}
@@ -0,0 +1,291 @@
+package roblox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit buckets. Requests that share a bucket share a budget.
+const (
+	bucketUserSettings   = "user-settings"
+	bucketTempScreenTime = "add-temporary-screentime"
+)
+
+// RateLimit is a budget of Limit requests per Window.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// DefaultRateLimits are the budgets Roblox documents, used until the
+// X-Ratelimit-* response headers say otherwise. Endpoints without a seeded
+// budget are only limited once Roblox reports one.
+var DefaultRateLimits = map[string]RateLimit{
+	bucketUserSettings:   {Limit: 30, Window: 60 * time.Second},
+	bucketTempScreenTime: {Limit: 5, Window: 60 * time.Second},
+}
+
+// RateLimitPolicy controls what the client does when a budget is exhausted,
+// either locally or because Roblox answered 429.
+type RateLimitPolicy struct {
+	// MaxWait is the longest the client will wait for budget before failing
+	// with ErrRateLimited. Zero fails fast.
+	MaxWait time.Duration
+	// MaxRetries is how many 429 responses a single call retries.
+	MaxRetries int
+	// OnWait, if set, is called before the client sleeps.
+	OnWait func(bucket string, d time.Duration)
+}
+
+// DefaultRateLimitPolicy waits up to a minute and retries a 429 twice.
+var DefaultRateLimitPolicy = RateLimitPolicy{MaxWait: time.Minute, MaxRetries: 2}
+
+// WithRateLimitPolicy sets how the client reacts to rate limits.
+func WithRateLimitPolicy(p RateLimitPolicy) Option {
+	return func(c *Client) {
+		c.rateLimitPolicy = p
+	}
+}
+
+// RateLimitError is returned when honoring a budget would mean waiting
+// longer than RateLimitPolicy.MaxWait.
+type RateLimitError struct {
+	Bucket     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited on %s, retry in %d seconds", e.Bucket, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// Is matches ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter reports how long to wait before retrying a rate limited call,
+// or zero when err does not say.
+func RetryAfter(err error) time.Duration {
+	var rlErr *RateLimitError
+	if errors.As(err, &rlErr) {
+		return rlErr.RetryAfter
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// limiter holds one token bucket per rate limit bucket name.
+type limiter struct {
+	mu      sync.Mutex
+	seed    map[string]RateLimit
+	buckets map[string]*bucket
+
+	// now and sleep are the clock buckets are refilled by, replaced in
+	// tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newLimiter(seed map[string]RateLimit) *limiter {
+	return &limiter{seed: seed, buckets: map[string]*bucket{}, now: time.Now, sleep: sleep}
+}
+
+// get returns the bucket for name, creating it from the seed. Unseeded
+// buckets start unlimited when create is false.
+func (l *limiter) get(name string, create bool) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[name]; ok {
+		return b
+	}
+	rl, seeded := l.seed[name]
+	if !seeded && !create {
+		return nil
+	}
+	b := &bucket{limit: rl.Limit, window: rl.Window, tokens: float64(rl.Limit), last: l.now()}
+	l.buckets[name] = b
+	return b
+}
+
+// wait blocks until the bucket has budget for one more request.
+func (l *limiter) wait(ctx context.Context, name string, policy RateLimitPolicy) error {
+	b := l.get(name, false)
+	if b == nil {
+		return nil
+	}
+	for {
+		d := b.reserve(l.now())
+		if d <= 0 {
+			return nil
+		}
+		if !canWait(ctx, d, policy) {
+			return &RateLimitError{Bucket: name, RetryAfter: d}
+		}
+		if policy.OnWait != nil {
+			policy.OnWait(name, d)
+		}
+		if err := l.sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// update applies the X-Ratelimit-* headers of a response.
+func (l *limiter) update(name string, h http.Header) {
+	if h.Get("X-Ratelimit-Limit") == "" && h.Get("X-Ratelimit-Remaining") == "" {
+		return
+	}
+	l.get(name, true).update(h, l.now())
+}
+
+// block stops the bucket from sending for d, after a 429.
+func (l *limiter) block(name string, d time.Duration) {
+	b := l.get(name, true)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := l.now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// bucket is a token bucket refilled continuously at limit per window.
+type bucket struct {
+	mu           sync.Mutex
+	limit        int
+	window       time.Duration
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// reserve takes a token and returns zero, or returns how long to wait before
+// one is available without taking it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	if b.limit <= 0 || b.window <= 0 {
+		return 0
+	}
+
+	rate := float64(b.limit) / b.window.Seconds()
+	b.tokens = math.Min(float64(b.limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// status reports the bucket's budget at now without taking from it.
+func (b *bucket) status(now time.Time) RateLimitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := RateLimitStatus{Limit: b.limit, Window: b.window}
+	if b.limit <= 0 || b.window <= 0 {
+		return s
+	}
+	rate := float64(b.limit) / b.window.Seconds()
+	tokens := math.Min(float64(b.limit), b.tokens+max(now.Sub(b.last).Seconds(), 0)*rate)
+	s.Remaining = int(tokens)
+	if now.Before(b.blockedUntil) {
+		s.Remaining = 0
+		s.RetryAfter = b.blockedUntil.Sub(now)
+	} else if tokens < 1 {
+		s.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return s
+}
+
+// update syncs the bucket with Roblox's view of it. Roblox sends, e.g.:
+//
+//	X-Ratelimit-Limit: 30, 30;w=60
+//	X-Ratelimit-Remaining: 29
+//	X-Ratelimit-Reset: 47
+func (b *bucket) update(h http.Header, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit, window, ok := parseRateLimitHeader(h.Get("X-Ratelimit-Limit")); ok {
+		b.limit, b.window = limit, window
+	}
+	remaining, err := strconv.Atoi(strings.TrimSpace(h.Get("X-Ratelimit-Remaining")))
+	if err != nil {
+		return
+	}
+	b.tokens = float64(remaining)
+	b.last = now
+	if remaining <= 0 {
+		if reset, err := strconv.Atoi(strings.TrimSpace(h.Get("X-Ratelimit-Reset"))); err == nil {
+			// The window starts over at the reset, with the full budget.
+			b.blockedUntil = now.Add(time.Duration(reset) * time.Second)
+			b.tokens, b.last = float64(b.limit), b.blockedUntil
+		}
+	}
+}
+
+// parseRateLimitHeader parses "30, 30;w=60" into a limit and window. The
+// first number is the limit that applies; it is followed by every policy,
+// as in "30, 30;w=60, 500;w=3600", and the window is that of the policy
+// with the same limit. Without one, the window is a minute.
+func parseRateLimitHeader(v string) (int, time.Duration, bool) {
+	first, policies, _ := strings.Cut(v, ",")
+	limit, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || limit < 0 {
+		return 0, 0, false
+	}
+	window := 60 * time.Second
+	for _, policy := range strings.Split(policies, ",") {
+		quota, params, _ := strings.Cut(policy, ";")
+		if n, err := strconv.Atoi(strings.TrimSpace(quota)); err != nil || n != limit {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if w, ok := strings.CutPrefix(strings.TrimSpace(param), "w="); ok {
+				if secs, err := strconv.Atoi(w); err == nil && secs > 0 {
+					return limit, time.Duration(secs) * time.Second, true
+				}
+			}
+		}
+	}
+	return limit, window, true
+}
+
+// canWait reports whether waiting d is within the policy and ends before
+// ctx's deadline. There is no point sleeping past the deadline only to fail
+// with a timeout instead of the rate limit.
+func canWait(ctx context.Context, d time.Duration, policy RateLimitPolicy) bool {
+	if d > policy.MaxWait {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,291 @@
+package roblox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeClock stands in for the limiter's clock: sleeping advances it.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func newFakeLimiter(seed map[string]RateLimit) (*limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 12, 14, 12, 0, 0, 0, time.UTC)}
+	l := newLimiter(seed)
+	l.now, l.sleep = clock.Now, clock.Sleep
+	return l, clock
+}
+
+func TestParseRateLimitHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		limit  int
+		window time.Duration
+		ok     bool
+	}{
+		{"30, 30;w=60", 30, time.Minute, true},
+		{"5, 5;w=10", 5, 10 * time.Second, true},
+		{"30", 30, time.Minute, true},
+		{" 30 , 30 ; w=60 ", 30, time.Minute, true},
+		{"30, 30;w=60, 500;w=3600", 30, time.Minute, true},
+		{"500, 30;w=60, 500;w=3600", 500, time.Hour, true},
+		{"30, 30;comment=x;w=15", 30, 15 * time.Second, true},
+		{"30, 30;w=abc", 30, time.Minute, true},
+		{"30, 30;w=-5", 30, time.Minute, true},
+		{"30, 30;w=0", 30, time.Minute, true},
+		{"30, 10;w=5", 30, time.Minute, true},
+		{"", 0, 0, false},
+		{"abc", 0, 0, false},
+		{"-1, -1;w=60", 0, 0, false},
+		{";w=60", 0, 0, false},
+	}
+	for _, tt := range tests {
+		limit, window, ok := parseRateLimitHeader(tt.header)
+		if limit != tt.limit || window != tt.window || ok != tt.ok {
+			t.Errorf("parseRateLimitHeader(%q) = %d, %v, %v, want %d, %v, %v", tt.header, limit, window, ok, tt.limit, tt.window, tt.ok)
+		}
+	}
+}
+
+func TestBucketReserve(t *testing.T) {
+	start := time.Date(2025, 12, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		bucket *bucket
+		at     []time.Duration // offsets from start of each reserve
+		want   []time.Duration // wait each returns, 0 when a token was taken
+	}{
+		{
+			name:   "budget then wait for refill",
+			bucket: &bucket{limit: 2, window: time.Minute, tokens: 2, last: start},
+			at:     []time.Duration{0, 0, 0},
+			want:   []time.Duration{0, 0, 30 * time.Second},
+		},
+		{
+			name:   "refills over time",
+			bucket: &bucket{limit: 2, window: time.Minute, tokens: 0, last: start},
+			at:     []time.Duration{10 * time.Second, 30 * time.Second, 30 * time.Second},
+			want:   []time.Duration{20 * time.Second, 0, 30 * time.Second},
+		},
+		{
+			name:   "refill stops at the limit",
+			bucket: &bucket{limit: 2, window: time.Minute, tokens: 0, last: start},
+			at:     []time.Duration{time.Hour, time.Hour, time.Hour},
+			want:   []time.Duration{0, 0, 30 * time.Second},
+		},
+		{
+			name:   "blocked after a 429",
+			bucket: &bucket{limit: 2, window: time.Minute, tokens: 2, last: start, blockedUntil: start.Add(5 * time.Second)},
+			at:     []time.Duration{0, 5 * time.Second},
+			want:   []time.Duration{5 * time.Second, 0},
+		},
+		{
+			name:   "no budget is unlimited",
+			bucket: &bucket{last: start},
+			at:     []time.Duration{0, 0, 0},
+			want:   []time.Duration{0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.bucket
+			for i, at := range tt.at {
+				if got := b.reserve(start.Add(at)); got != tt.want[i] {
+					t.Errorf("reserve %d at +%v = %v, want %v", i+1, at, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBucketUpdate(t *testing.T) {
+	now := time.Date(2025, 12, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		header  http.Header
+		limit   int
+		window  time.Duration
+		wait    time.Duration // reserve right after
+		blocked bool
+	}{
+		{
+			name:   "budget left",
+			header: http.Header{"X-Ratelimit-Limit": {"5, 5;w=10"}, "X-Ratelimit-Remaining": {"3"}},
+			limit:  5, window: 10 * time.Second,
+		},
+		{
+			name:   "used up",
+			header: http.Header{"X-Ratelimit-Limit": {"5, 5;w=10"}, "X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"7"}},
+			limit:  5, window: 10 * time.Second,
+			wait: 7 * time.Second, blocked: true,
+		},
+		{
+			name:   "used up without a reset",
+			header: http.Header{"X-Ratelimit-Limit": {"6, 6;w=60"}, "X-Ratelimit-Remaining": {"0"}},
+			limit:  6, window: time.Minute,
+			wait: 10 * time.Second,
+		},
+		{
+			name:   "malformed remaining keeps the tokens",
+			header: http.Header{"X-Ratelimit-Limit": {"5, 5;w=10"}, "X-Ratelimit-Remaining": {"lots"}},
+			limit:  5, window: 10 * time.Second,
+		},
+		{
+			name:   "malformed limit keeps the budget",
+			header: http.Header{"X-Ratelimit-Limit": {"many"}, "X-Ratelimit-Remaining": {"1"}},
+			limit:  30, window: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bucket{limit: 30, window: time.Minute, tokens: 30, last: now}
+			b.update(tt.header, now)
+			if b.limit != tt.limit || b.window != tt.window {
+				t.Errorf("budget %d per %v, want %d per %v", b.limit, b.window, tt.limit, tt.window)
+			}
+			if got := !b.blockedUntil.IsZero(); got != tt.blocked {
+				t.Errorf("blocked = %v, want %v", got, tt.blocked)
+			}
+			if got := b.reserve(now); got != tt.wait {
+				t.Errorf("reserve = %v, want %v", got, tt.wait)
+			}
+		})
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	seed := map[string]RateLimit{"seeded": {Limit: 1, Window: 10 * time.Second}}
+
+	t.Run("fails fast without MaxWait", func(t *testing.T) {
+		l, clock := newFakeLimiter(seed)
+		ctx := context.Background()
+		if err := l.wait(ctx, "seeded", RateLimitPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+		err := l.wait(ctx, "seeded", RateLimitPolicy{})
+		var rlErr *RateLimitError
+		if !errors.As(err, &rlErr) || !errors.Is(err, ErrRateLimited) || rlErr.RetryAfter != 10*time.Second {
+			t.Fatalf("err = %v, want a RateLimitError to retry in 10s", err)
+		}
+		if len(clock.sleeps) != 0 {
+			t.Errorf("slept %v", clock.sleeps)
+		}
+	})
+
+	t.Run("blocks until refilled", func(t *testing.T) {
+		l, clock := newFakeLimiter(seed)
+		var waited []time.Duration
+		policy := RateLimitPolicy{MaxWait: time.Minute, OnWait: func(_ string, d time.Duration) {
+			waited = append(waited, d)
+		}}
+		for range 3 {
+			if err := l.wait(context.Background(), "seeded", policy); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := []time.Duration{10 * time.Second, 10 * time.Second}
+		if len(clock.sleeps) != 2 || clock.sleeps[0] != want[0] || clock.sleeps[1] != want[1] {
+			t.Errorf("slept %v, want %v", clock.sleeps, want)
+		}
+		if len(waited) != 2 {
+			t.Errorf("OnWait called %d times, want 2", len(waited))
+		}
+	})
+
+	t.Run("blocked by a 429", func(t *testing.T) {
+		l, clock := newFakeLimiter(seed)
+		l.block("seeded", 25*time.Second)
+		if err := l.wait(context.Background(), "seeded", RateLimitPolicy{MaxWait: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+		if len(clock.sleeps) != 1 || clock.sleeps[0] != 25*time.Second {
+			t.Errorf("slept %v, want [25s]", clock.sleeps)
+		}
+	})
+
+	t.Run("unseeded bucket waits once Roblox reports a budget", func(t *testing.T) {
+		l, clock := newFakeLimiter(seed)
+		policy := RateLimitPolicy{MaxWait: time.Minute}
+		for range 3 {
+			if err := l.wait(context.Background(), "other", policy); err != nil {
+				t.Fatal(err)
+			}
+		}
+		l.update("other", http.Header{"X-Ratelimit-Limit": {"2, 2;w=60"}, "X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"12"}})
+		if err := l.wait(context.Background(), "other", policy); err != nil {
+			t.Fatal(err)
+		}
+		if len(clock.sleeps) != 1 || clock.sleeps[0] != 12*time.Second {
+			t.Errorf("slept %v, want [12s]", clock.sleeps)
+		}
+	})
+
+	t.Run("fails fast when the wait outlasts the deadline", func(t *testing.T) {
+		l, clock := newFakeLimiter(seed)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		policy := RateLimitPolicy{MaxWait: time.Minute}
+		if err := l.wait(ctx, "seeded", policy); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.wait(ctx, "seeded", policy); !errors.Is(err, ErrRateLimited) || RetryAfter(err) != 10*time.Second {
+			t.Errorf("err = %v, want to retry in 10s", err)
+		}
+		if len(clock.sleeps) != 0 {
+			t.Errorf("slept %v past the deadline", clock.sleeps)
+		}
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		l, _ := newFakeLimiter(seed)
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := RateLimitPolicy{MaxWait: time.Minute}
+		if err := l.wait(ctx, "seeded", policy); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if err := l.wait(ctx, "seeded", policy); !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestCanWait(t *testing.T) {
+	policy := RateLimitPolicy{MaxWait: time.Minute}
+	short, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		d      time.Duration
+		policy RateLimitPolicy
+		want   bool
+	}{
+		{"within MaxWait", context.Background(), 30 * time.Second, policy, true},
+		{"beyond MaxWait", context.Background(), 2 * time.Minute, policy, false},
+		{"fail fast", context.Background(), time.Second, RateLimitPolicy{}, false},
+		{"before the deadline", short, time.Second, policy, true},
+		{"past the deadline", short, 30 * time.Second, policy, false},
+	}
+	for _, tt := range tests {
+		if got := canWait(tt.ctx, tt.d, tt.policy); got != tt.want {
+			t.Errorf("%s: canWait(%v) = %v, want %v", tt.name, tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package roblox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxCSRFRetries bounds how many times a single call resends a POST after
+// Roblox rotates the CSRF token. A server that rotates on every attempt gets
+// an ErrCSRFRejected instead of an endless loop.
+const maxCSRFRetries = 1
+
+// request describes one API call for Client.do.
+type request struct {
+	method string
+	url    string
+	body   any    // JSON-encoded when non-nil
+	public bool   // send no cookies (public endpoints)
+	bucket string // rate limit bucket; defaults to the URL path
+	retry  retryMode
+	ok     []int // accepted status codes; defaults to 200
+
+	// csrfOnly sends a POST without a token only to be handed a fresh
+	// one. The token is stored and the rejection returned as success.
+	csrfOnly bool
+}
+
+// do sends r and returns the response when its status is accepted. Any other
+// status is returned as an *APIError with the body already consumed. The
+// caller closes the returned body.
+//
+// Every request goes through here:
+//   - the endpoint's rate limit budget is spent, waiting if the policy allows,
+//     and updated from the X-Ratelimit-* headers of the response;
+//   - network errors and 5xx responses are retried per RetryPolicy, within
+//     what r.retry allows;
+//   - a 429 is retried after Retry-After or an exponential backoff, up to
+//     RateLimitPolicy.MaxRetries times;
+//   - POSTs send the cached CSRF token, and a 403 that rotates the token is
+//     retried with the new one, at most maxCSRFRetries times;
+//   - cookies come from the client's jar, and any Set-Cookie on the
+//     response goes back into it, so rotated session cookies are used from
+//     then on and reported to the WithCredentialsRotation handler;
+//   - the Date header is noted, so ClockSkew can compare clocks.
+func (c *Client) do(ctx context.Context, r request) (*http.Response, error) {
+	var payload []byte
+	if r.body != nil {
+		var err error
+		if payload, err = json.Marshal(r.body); err != nil {
+			return nil, err
+		}
+	}
+
+	ok := r.ok
+	if len(ok) == 0 {
+		ok = []int{http.StatusOK}
+	}
+
+	bucket := r.bucket
+	if bucket == "" {
+		if u, err := url.Parse(r.url); err == nil {
+			bucket = u.Path
+		}
+	}
+	policy := c.rateLimitPolicy
+
+	csrfRetries, rateRetries, attempts := 0, 0, 0
+	for {
+		attempts++
+		if err := c.limiter.wait(ctx, bucket, policy); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, r.method, r.url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if !r.public {
+			c.addCookies(req)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		sentToken := ""
+		if r.method == http.MethodPost && !r.csrfOnly {
+			sentToken = c.loadCSRFToken()
+			if sentToken != "" {
+				req.Header.Set(csrfTokenHeader, sentToken)
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() == nil && attempts < c.retryPolicy.MaxAttempts && retryable(r.retry, err, 0) {
+				if err := c.retryPolicy.backoff(ctx, attempts); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		c.limiter.update(bucket, resp.Header)
+		c.noteServerDate(resp.Header)
+		if !r.public {
+			c.updateCookies(resp)
+		}
+		if slices.Contains(ok, resp.StatusCode) {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(resp)
+		resp.Body.Close()
+
+		switch {
+		case attempts < c.retryPolicy.MaxAttempts && retryable(r.retry, nil, resp.StatusCode):
+			if err := c.retryPolicy.backoff(ctx, attempts); err != nil {
+				return nil, err
+			}
+			continue
+		case errors.Is(apiErr, ErrCSRFRejected):
+			newToken := resp.Header.Get(csrfTokenHeader)
+			if r.csrfOnly && newToken != "" {
+				c.storeCSRFToken(newToken)
+				return nil, nil
+			}
+			if newToken != "" && newToken != sentToken && csrfRetries < maxCSRFRetries {
+				c.storeCSRFToken(newToken)
+				csrfRetries++
+				continue
+			}
+		case errors.Is(apiErr, ErrRateLimited):
+			// A 429 means the request was not processed, so even
+			// non-idempotent calls are safe to resend.
+			wait := max(apiErr.RetryAfter, time.Second<<rateRetries)
+			if apiErr.RetryAfter == 0 {
+				apiErr.RetryAfter = wait
+			}
+			c.limiter.block(bucket, wait)
+			if rateRetries < policy.MaxRetries && canWait(ctx, wait, policy) {
+				rateRetries++
+				continue
+			}
+		}
+		return nil, apiErr
+	}
+}
+
+func (c *Client) loadCSRFToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.csrfToken == "" && c.tokenCache != nil {
+		c.csrfToken = c.tokenCache.LoadToken()
+	}
+	return c.csrfToken
+}
+
+func (c *Client) storeCSRFToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.csrfToken = token
+	if c.tokenCache != nil {
+		c.tokenCache.SaveToken(token)
+	}
+}
+
+// noteServerDate records how far the local clock is from the server's.
+func (c *Client) noteServerDate(h http.Header) {
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return
+	}
+	skew := c.now().Sub(date)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clockSkew = &skew
+}
+
+// TokenCache persists the CSRF token between clients, so a short-lived
+// process such as the CLI can skip the round trip that obtains a fresh one.
+// A stale token is harmless: Roblox rejects it and hands out a new one.
+type TokenCache interface {
+	LoadToken() string
+	SaveToken(token string)
+}
+
+// FileTokenCache returns a TokenCache backed by the file at path. Errors are
+// ignored; a cache that cannot be read or written just means an extra round
+// trip.
+func FileTokenCache(path string) TokenCache {
+	return fileTokenCache(path)
+}
+
+type fileTokenCache string
+
+func (f fileTokenCache) LoadToken() string {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (f fileTokenCache) SaveToken(token string) {
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0700); err != nil {
+		return
+	}
+	os.WriteFile(string(f), []byte(token+"\n"), 0600)
+}
+
+// discard drains and closes a response body the caller has no use for.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
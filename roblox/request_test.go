@@ -0,0 +1,80 @@
+package roblox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/astrostl/blockblox/fakeroblox"
+	"github.com/astrostl/blockblox/roblox"
+)
+
+const settingsPath = "/user-settings-api/v1/user-settings"
+
+func TestCSRFRetries(t *testing.T) {
+	addTime := func(c *roblox.Client) error {
+		return c.AddTemporaryScreenTime(context.Background(), 15)
+	}
+	setLimit := func(c *roblox.Client) error {
+		return c.SetScreenTime(context.Background(), 90)
+	}
+
+	tests := []struct {
+		name     string
+		always   bool // rotate on every POST rather than once
+		call     func(*roblox.Client) error
+		path     string
+		wantErr  error
+		requests int
+	}{
+		{name: "temporary time, rotated once", call: addTime, path: tempTimePath, requests: 2},
+		{name: "settings, rotated once", call: setLimit, path: settingsPath, requests: 2},
+		{name: "temporary time, rotated always", always: true, call: addTime, path: tempTimePath, wantErr: roblox.ErrCSRFRejected, requests: 2},
+		{name: "settings, rotated always", always: true, call: setLimit, path: settingsPath, wantErr: roblox.ErrCSRFRejected, requests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeroblox.New()
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			client := newTestClient(t, server, ts.URL, nil)
+			if tt.always {
+				server.RotateCSRFTokenAlways(true)
+			} else {
+				server.RotateCSRFToken()
+			}
+
+			if err := tt.call(client); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// The first POST, then exactly one retry with the new token.
+			if got := server.Requests(http.MethodPost, tt.path); got != tt.requests {
+				t.Errorf("%d POSTs reached the server, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRateLimitRetryPastDeadline(t *testing.T) {
+	server := fakeroblox.New(fakeroblox.WithClock(fakeroblox.FixedClock(time.Date(2025, 12, 14, 12, 0, 0, 0, time.UTC))))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	server.SetRateLimit(fakeroblox.BucketTempScreenTime, 1, time.Minute)
+	server.ExhaustRateLimit(fakeroblox.BucketTempScreenTime)
+	client := newTestClient(t, server, ts.URL, nil)
+
+	// Retry-After is a minute, within MaxWait but past the deadline, so
+	// the 429 is returned at once rather than waited out into a timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.AddTemporaryScreenTime(ctx, 15)
+	if !errors.Is(err, roblox.ErrRateLimited) || roblox.RetryAfter(err) != time.Minute {
+		t.Fatalf("err = %v, want rate limited for a minute", err)
+	}
+	if got := server.Requests(http.MethodPost, tempTimePath); got != 1 {
+		t.Errorf("%d POSTs reached the server, want 1", got)
+	}
+}
@@ -0,0 +1,126 @@
+package roblox
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how transient failures (network errors and 5xx
+// responses) are retried.
+//
+// Only idempotent calls are retried on any transient failure; failures that
+// would only repeat, such as an untrusted TLS certificate, are not retried.
+// AddTemporaryScreenTime is retried only when the request provably never
+// reached Roblox (the connection could not be made), so minutes are never
+// granted twice.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled for each one after
+	MaxDelay    time.Duration // cap on a single delay
+	Jitter      float64       // fraction of each delay that is randomized, 0 to 1
+}
+
+// DefaultRetryPolicy makes up to three attempts, half a second then one
+// second apart, give or take half.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+}
+
+// WithRetryPolicy sets how transient failures are retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = p
+	}
+}
+
+// retryMode says which failures a request may be resent after.
+type retryMode int
+
+const (
+	// retryIdempotent resends after any transient network error or 5xx.
+	retryIdempotent retryMode = iota
+	// retryUnsent resends only when the request never left the machine.
+	retryUnsent
+)
+
+// delay returns the backoff before retry number n (starting at 1).
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		spread := float64(d) * min(p.Jitter, 1)
+		d = time.Duration(float64(d) - spread + rand.Float64()*2*spread)
+	}
+	return d
+}
+
+// retryable reports whether a failed attempt may be resent under mode. Either
+// err (no response) or status (response received) describes the failure.
+func retryable(mode retryMode, err error, status int) bool {
+	if err != nil {
+		if mode == retryUnsent {
+			return notSent(err)
+		}
+		return !permanent(err)
+	}
+	if mode == retryUnsent {
+		return false
+	}
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// notSent reports whether err happened before any of the request could have
+// been written: DNS failures and refused or unreachable connections.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// permanent reports whether err would recur however often the request is
+// resent: the server's certificate cannot be verified, or the TLS handshake
+// failed.
+func permanent(err error) bool {
+	var (
+		verifyErr *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+		certErr   x509.CertificateInvalidError
+		recordErr tls.RecordHeaderError
+		alertErr  tls.AlertError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownCA) ||
+		errors.As(err, &hostErr) || errors.As(err, &certErr) ||
+		errors.As(err, &recordErr) || errors.As(err, &alertErr)
+}
+
+// backoff sleeps before retry number n, or returns early when ctx is done.
+func (p RetryPolicy) backoff(ctx context.Context, n int) error {
+	t := time.NewTimer(p.delay(n))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+package roblox_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/astrostl/blockblox/fakeroblox"
+	"github.com/astrostl/blockblox/roblox"
+)
+
+const (
+	userPath     = "/v1/users/authenticated"
+	tempTimePath = "/parental-controls-api/v1/parental-controls/add-temporary-screentime"
+)
+
+// newTestClient returns a client for server at url that retries without
+// waiting. It starts with the server's CSRF token, so each attempt at a POST
+// reaches the server once.
+func newTestClient(t *testing.T, server *fakeroblox.Server, url string, hc *http.Client) *roblox.Client {
+	t.Helper()
+	tokenPath := filepath.Join(t.TempDir(), "csrf")
+	if err := os.WriteFile(tokenPath, []byte(server.State().CSRFToken), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := []roblox.Option{
+		roblox.WithEndpoints(roblox.SingleHost(url)),
+		roblox.WithCredentials(roblox.StaticCredentials(server.Credentials())),
+		roblox.WithCSRFTokenCache(roblox.FileTokenCache(tokenPath)),
+		roblox.WithRetryPolicy(roblox.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	}
+	if hc != nil {
+		opts = append(opts, roblox.WithHTTPClient(hc))
+	}
+	client, err := roblox.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// failingDialer returns an HTTP client whose first failures dials are
+// refused, and a count of every dial.
+func failingDialer(failures int32) (*http.Client, *atomic.Int32) {
+	var dials atomic.Int32
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if dials.Add(1) <= failures {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	return &http.Client{Transport: transport}, &dials
+}
+
+func TestRetries(t *testing.T) {
+	getUser := func(c *roblox.Client) error {
+		_, err := c.GetUser(context.Background())
+		return err
+	}
+	addTime := func(c *roblox.Client) error {
+		return c.AddTemporaryScreenTime(context.Background(), 15)
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(s *fakeroblox.Server)
+		failDials int32
+		call      func(*roblox.Client) error
+		path      string
+		wantErr   bool
+		requests  int // reaching the server
+		dials     int32
+		granted   int // temporary minutes
+	}{
+		{
+			name:     "5xx is retried when idempotent",
+			setup:    func(s *fakeroblox.Server) { s.FailRequests(2, http.StatusServiceUnavailable) },
+			call:     getUser,
+			path:     userPath,
+			requests: 3,
+		},
+		{
+			name:     "5xx gives up after MaxAttempts",
+			setup:    func(s *fakeroblox.Server) { s.FailRequests(5, http.StatusBadGateway) },
+			call:     getUser,
+			path:     userPath,
+			wantErr:  true,
+			requests: 3,
+		},
+		{
+			name:     "5xx is not retried for temporary time",
+			setup:    func(s *fakeroblox.Server) { s.FailRequests(1, http.StatusServiceUnavailable) },
+			call:     addTime,
+			path:     tempTimePath,
+			wantErr:  true,
+			requests: 1,
+		},
+		{
+			name:     "reset after sending is retried when idempotent",
+			setup:    func(s *fakeroblox.Server) { s.DropConnections(1) },
+			call:     getUser,
+			path:     userPath,
+			requests: 2,
+		},
+		{
+			name:     "reset after sending is not retried for temporary time",
+			setup:    func(s *fakeroblox.Server) { s.DropConnections(1) },
+			call:     addTime,
+			path:     tempTimePath,
+			wantErr:  true,
+			requests: 1,
+			granted:  15,
+		},
+		{
+			name:      "dial failure is retried for temporary time",
+			failDials: 1,
+			call:      addTime,
+			path:      tempTimePath,
+			requests:  1,
+			dials:     2,
+			granted:   15,
+		},
+		{
+			name:      "dial failure gives up after MaxAttempts",
+			failDials: 3,
+			call:      addTime,
+			path:      tempTimePath,
+			wantErr:   true,
+			dials:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeroblox.New()
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+			hc, dials := failingDialer(tt.failDials)
+			client := newTestClient(t, server, ts.URL, hc)
+
+			err := tt.call(client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			method := http.MethodGet
+			if tt.path == tempTimePath {
+				method = http.MethodPost
+			}
+			if got := server.Requests(method, tt.path); got != tt.requests {
+				t.Errorf("%d requests reached the server, want %d", got, tt.requests)
+			}
+			if tt.dials > 0 && dials.Load() != tt.dials {
+				t.Errorf("%d dials, want %d", dials.Load(), tt.dials)
+			}
+			if got := server.TemporaryTime(); got != tt.granted {
+				t.Errorf("%d temporary minutes granted, want %d", got, tt.granted)
+			}
+		})
+	}
+}
+
+func TestUntrustedCertificateIsNotRetried(t *testing.T) {
+	server := fakeroblox.New()
+	ts := httptest.NewUnstartedServer(server)
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0) // the refused handshake
+	ts.StartTLS()
+	defer ts.Close()
+	// The test server's certificate is not in the client's roots.
+	hc, dials := failingDialer(0)
+	client := newTestClient(t, server, ts.URL, hc)
+
+	_, err := client.GetUser(context.Background())
+	if err == nil {
+		t.Fatal("GetUser trusted the test certificate")
+	}
+	var verifyErr *tls.CertificateVerificationError
+	if !errors.As(err, &verifyErr) {
+		t.Errorf("err = %v, want a certificate verification error", err)
+	}
+	if dials.Load() != 1 {
+		t.Errorf("%d dials, want 1", dials.Load())
+	}
+}
@@ -0,0 +1,190 @@
+package roblox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RequirementSelfUpdate marks an option the signed-in account may choose for
+// itself. Other requirements (such as parental consent) cannot be met from
+// the account alone.
+const RequirementSelfUpdate = "SelfUpdateSetting"
+
+// Option types Roblox uses for free-form values.
+const (
+	OptionTypeInteger = "Integer"
+	OptionTypeBoolean = "Boolean"
+)
+
+// SettingDailyScreenTimeLimit is the key of the daily limit, in minutes.
+const SettingDailyScreenTimeLimit = "dailyScreenTimeLimit"
+
+// ErrNotSelfUpdatable is returned when a setting has no option the account
+// may choose for itself.
+var ErrNotSelfUpdatable = errors.New("setting cannot be changed by this account")
+
+// Settings is the whole settings-and-options catalog, keyed by setting name.
+type Settings map[string]Setting
+
+// Setting is one user setting with its current value and the options it
+// advertises.
+type Setting struct {
+	CurrentValue json.RawMessage `json:"currentValue"`
+	Options      []SettingOption `json:"options"`
+}
+
+// SettingOption is one choice for a setting and what it takes to select it.
+type SettingOption struct {
+	Option      OptionValue `json:"option"`
+	Requirement string      `json:"requirement"`
+}
+
+// OptionValue is either a fixed value (OptionValue) or a type of free-form
+// value (OptionType, e.g. "Integer").
+type OptionValue struct {
+	OptionType  string          `json:"optionType,omitempty"`
+	OptionValue json.RawMessage `json:"optionValue,omitempty"`
+}
+
+// Keys returns the setting names in order.
+func (s Settings) Keys() []string {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// Current returns the current value for display: strings unquoted, anything
+// else as JSON.
+func (s Setting) Current() string {
+	return displayJSON(s.CurrentValue)
+}
+
+// SelfUpdatable reports whether the account may change the setting itself.
+func (s Setting) SelfUpdatable() bool {
+	for _, opt := range s.Options {
+		if opt.Requirement == RequirementSelfUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// Requirements returns the distinct requirements the options carry.
+func (s Setting) Requirements() []string {
+	var reqs []string
+	for _, opt := range s.Options {
+		if opt.Requirement != "" && !slices.Contains(reqs, opt.Requirement) {
+			reqs = append(reqs, opt.Requirement)
+		}
+	}
+	return reqs
+}
+
+// Choices describes the values the account may choose for itself, e.g.
+// ["AllUsers", "Friends"] or ["<Integer>"].
+func (s Setting) Choices() []string {
+	var choices []string
+	for _, opt := range s.Options {
+		if opt.Requirement != RequirementSelfUpdate {
+			continue
+		}
+		if opt.Option.OptionType != "" {
+			choices = append(choices, "<"+opt.Option.OptionType+">")
+		} else if len(opt.Option.OptionValue) > 0 {
+			choices = append(choices, displayJSON(opt.Option.OptionValue))
+		}
+	}
+	return choices
+}
+
+// ParseValue validates raw against the options the account may choose and
+// returns the value to send in an update.
+func (s Setting) ParseValue(raw string) (any, error) {
+	if !s.SelfUpdatable() {
+		if reqs := s.Requirements(); len(reqs) > 0 {
+			return nil, fmt.Errorf("%w (requires %s)", ErrNotSelfUpdatable, strings.Join(reqs, ", "))
+		}
+		return nil, ErrNotSelfUpdatable
+	}
+
+	for _, opt := range s.Options {
+		if opt.Requirement != RequirementSelfUpdate {
+			continue
+		}
+		switch {
+		case opt.Option.OptionType == OptionTypeInteger:
+			if n, err := strconv.Atoi(raw); err == nil {
+				return n, nil
+			}
+		case opt.Option.OptionType == OptionTypeBoolean:
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b, nil
+			}
+		case len(opt.Option.OptionValue) > 0:
+			if displayJSON(opt.Option.OptionValue) == raw {
+				var v any
+				if err := json.Unmarshal(opt.Option.OptionValue, &v); err == nil {
+					return v, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid value %q (choose from: %s)", raw, strings.Join(s.Choices(), ", "))
+}
+
+// displayJSON renders a JSON value for people: strings without quotes,
+// anything else as written.
+func displayJSON(raw json.RawMessage) string {
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return str
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+// GetSettings returns every user setting with its current value and options.
+func (c *Client) GetSettings(ctx context.Context) (Settings, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: c.endpoints.settingsURL(), bucket: bucketUserSettings})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var settings Settings
+	if err := decodeJSON(resp, &settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings changes the given settings, leaving the rest alone. Values
+// are sent as-is; use Setting.ParseValue to validate them first. Setting
+// absolute values is idempotent, so transient failures are retried like a
+// GET.
+func (c *Client) UpdateSettings(ctx context.Context, values map[string]any) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, request{
+		method: http.MethodPost,
+		url:    c.endpoints.updateURL(),
+		bucket: bucketUserSettings,
+		body:   values,
+	})
+	if err != nil {
+		return err
+	}
+	discard(resp)
+
+	return nil
+}
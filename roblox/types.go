@@ -0,0 +1,48 @@
+package roblox
+
+// Restriction sources reported by the not-approved endpoint.
+const (
+	RestrictionSourceBan        = 1
+	RestrictionSourceScreenTime = 2
+)
+
+// UserResponse identifies a Roblox user.
+type UserResponse struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// DailyScreentime is one day of the weekly screen time report.
+type DailyScreentime struct {
+	DaysAgo       int `json:"daysAgo"`
+	MinutesPlayed int `json:"minutesPlayed"`
+}
+
+// WeeklyScreentimeResponse is the body of get-weekly-screentime.
+type WeeklyScreentimeResponse struct {
+	DailyScreentimes []DailyScreentime `json:"dailyScreentimes"`
+	LocalDayOfWeek   int               `json:"localDayOfWeek"` // 0 = Sunday
+}
+
+// Restriction describes why the account is currently not approved to play.
+type Restriction struct {
+	Source           int    `json:"source"`
+	ModerationStatus int    `json:"moderationStatus"`
+	StartTime        string `json:"startTime"`
+	EndTime          string `json:"endTime"`
+	DurationSeconds  int    `json:"durationSeconds"`
+}
+
+// RestrictionResponse is the body of v2/not-approved.
+type RestrictionResponse struct {
+	Restriction *Restriction `json:"restriction"`
+}
+
+// BanDetails is the body of v1/not-approved for a banned account.
+type BanDetails struct {
+	PunishedUserId            int64  `json:"punishedUserId"`
+	MessageToUser             string `json:"messageToUser"`
+	PunishmentTypeDescription string `json:"punishmentTypeDescription"`
+	EndDate                   string `json:"endDate"`
+}
@@ -0,0 +1,103 @@
+package roblox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// daysPerWeek is how many days get-weekly-screentime reports.
+const daysPerWeek = 7
+
+// Day is one calendar day of screen time.
+type Day struct {
+	Date          time.Time // midnight, local time
+	DaysAgo       int       // 0 is today
+	MinutesPlayed int
+}
+
+// Weekday returns the day of the week Roblox counted the minutes against.
+func (d Day) Weekday() time.Weekday {
+	return d.Date.Weekday()
+}
+
+// WeeklyScreentime is the last seven days of screen time, oldest first.
+type WeeklyScreentime struct {
+	Days []Day
+}
+
+// Today returns today's entry.
+func (w *WeeklyScreentime) Today() Day {
+	return w.Days[len(w.Days)-1]
+}
+
+// Total returns the minutes played across the week.
+func (w *WeeklyScreentime) Total() int {
+	var total int
+	for _, d := range w.Days {
+		total += d.MinutesPlayed
+	}
+	return total
+}
+
+// Average returns the minutes played per day, rounded to the nearest minute.
+func (w *WeeklyScreentime) Average() int {
+	return (w.Total() + len(w.Days)/2) / len(w.Days)
+}
+
+// GetWeeklyScreentime returns the minutes played on each of the last seven
+// days, including today. Days Roblox leaves out are reported as zero.
+func (c *Client) GetWeeklyScreentime(ctx context.Context, userID int64) (*WeeklyScreentime, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url := fmt.Sprintf("%s?userId=%d", c.endpoints.weeklyScreentimeURL(), userID)
+	resp, err := c.do(ctx, request{method: http.MethodGet, url: url})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var weekly WeeklyScreentimeResponse
+	if err := decodeJSON(resp, &weekly); err != nil {
+		return nil, err
+	}
+	if weekly.LocalDayOfWeek < 0 || weekly.LocalDayOfWeek >= daysPerWeek {
+		return nil, &SchemaError{URL: url, Err: fmt.Errorf("localDayOfWeek %d out of range", weekly.LocalDayOfWeek)}
+	}
+
+	played := make([]int, daysPerWeek)
+	for _, day := range weekly.DailyScreentimes {
+		if day.DaysAgo < 0 || day.DaysAgo >= daysPerWeek {
+			return nil, &SchemaError{URL: url, Err: fmt.Errorf("daysAgo %d out of range", day.DaysAgo)}
+		}
+		played[day.DaysAgo] = day.MinutesPlayed
+	}
+
+	today := accountToday(c.now(), time.Weekday(weekly.LocalDayOfWeek))
+	days := make([]Day, daysPerWeek)
+	for i := range days {
+		daysAgo := daysPerWeek - 1 - i
+		days[i] = Day{
+			Date:          today.AddDate(0, 0, -daysAgo),
+			DaysAgo:       daysAgo,
+			MinutesPlayed: played[daysAgo],
+		}
+	}
+	return &WeeklyScreentime{Days: days}, nil
+}
+
+// accountToday returns midnight of the account's current day. Roblox counts
+// days in the account's time zone, which can be a day either side of this
+// machine's, so the local date is moved to the nearest one falling on
+// weekday.
+func accountToday(now time.Time, weekday time.Weekday) time.Time {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	diff := (int(weekday) - int(today.Weekday()) + daysPerWeek) % daysPerWeek
+	if diff > daysPerWeek/2 {
+		diff -= daysPerWeek
+	}
+	return today.AddDate(0, 0, diff)
+}
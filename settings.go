@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/astrostl/blockblox/roblox"
+)
+
+// settingsUsage follows mistakes in the settings subcommand.
+var settingsUsage = []string{
+	"Usage:",
+	"  blockblox settings list                 List every setting and its value",
+	"  blockblox settings get <key>            Show one setting and its options",
+	"  blockblox settings set <key> <value>    Change a setting",
+}
+
+func (a *app) settings(args []string) int {
+	if len(args) < 1 {
+		return a.usage("missing settings subcommand", settingsUsage...)
+	}
+
+	switch args[0] {
+	case "list":
+		return a.settingsList()
+	case "get":
+		if len(args) < 2 {
+			return a.usage("missing setting key", "Usage: blockblox settings get <key>")
+		}
+		return a.settingsGet(args[1])
+	case "set":
+		if len(args) < 3 {
+			return a.usage("missing setting key or value", "Usage: blockblox settings set <key> <value>")
+		}
+		return a.settingsSet(args[1], args[2])
+	default:
+		return a.usage("unknown settings subcommand: "+args[0], settingsUsage...)
+	}
+}
+
+func (a *app) settingsList() int {
+	settings, err := a.client.GetSettings(a.ctx)
+	if err != nil {
+		if msg, code := a.restrictionMessage(); msg != "" {
+			fmt.Fprintln(a.stderr, msg)
+			return code
+		}
+		return a.fail("Error getting settings", err)
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tOPTIONS")
+	for _, key := range settings.Keys() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, settings[key].Current(), describeChoices(settings[key]))
+	}
+	tw.Flush()
+	return 0
+}
+
+func (a *app) settingsGet(key string) int {
+	setting, code := a.lookupSetting(key)
+	if code != 0 {
+		return code
+	}
+
+	fmt.Fprintf(a.stdout, "%s: %s\n", key, setting.Current())
+	fmt.Fprintf(a.stdout, "Options: %s\n", describeChoices(setting))
+	return 0
+}
+
+func (a *app) settingsSet(key, raw string) int {
+	setting, code := a.lookupSetting(key)
+	if code != 0 {
+		return code
+	}
+
+	value, err := setting.ParseValue(raw)
+	if err != nil {
+		return a.usage(fmt.Sprintf("%s: %v", key, err))
+	}
+
+	if err := a.client.UpdateSettings(a.ctx, map[string]any{key: value}); err != nil {
+		return a.fail("Error updating setting", err)
+	}
+
+	fmt.Fprintf(a.stdout, "%s set to %s\n", key, raw)
+	return 0
+}
+
+// lookupSetting fetches the catalog and picks key out of it. A non-zero code
+// means the failure has been reported.
+func (a *app) lookupSetting(key string) (roblox.Setting, int) {
+	settings, err := a.client.GetSettings(a.ctx)
+	if err != nil {
+		if msg, code := a.restrictionMessage(); msg != "" {
+			fmt.Fprintln(a.stderr, msg)
+			return roblox.Setting{}, code
+		}
+		return roblox.Setting{}, a.fail("Error getting settings", err)
+	}
+
+	setting, ok := settings[key]
+	if !ok {
+		return roblox.Setting{}, a.usage(fmt.Sprintf("unknown setting %q", key), "Run 'blockblox settings list' to see available settings")
+	}
+	return setting, 0
+}
+
+// describeChoices lists what the account may set, or why it cannot.
+func describeChoices(s roblox.Setting) string {
+	if !s.SelfUpdatable() {
+		if reqs := s.Requirements(); len(reqs) > 0 {
+			return fmt.Sprintf("(read-only: requires %s)", strings.Join(reqs, ", "))
+		}
+		return "(read-only)"
+	}
+	return strings.Join(s.Choices(), ", ")
+}
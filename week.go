@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+)
+
+// week prints the last seven days of screen time against the current limit.
+func (a *app) week() int {
+	user, err := a.user()
+	if err != nil {
+		if msg, code := a.restrictionMessage(); msg != "" {
+			fmt.Fprintln(a.stderr, msg)
+			return code
+		}
+		return a.fail("Error getting user", err)
+	}
+	fmt.Fprintf(a.stdout, "User: %s (@%s)\n", user.DisplayName, user.Name)
+
+	// Roblox refuses the report while the account is restricted
+	if msg, code := a.restrictionMessage(); msg != "" {
+		fmt.Fprintf(a.stderr, "\n%s\n", msg)
+		return code
+	}
+
+	limit, err := a.client.GetScreenTime(a.ctx)
+	if err != nil {
+		return a.fail("Error getting screen time", err)
+	}
+	weekly, err := a.client.GetWeeklyScreentime(a.ctx, user.ID)
+	if err != nil {
+		return a.fail("Error getting weekly screen time", err)
+	}
+	limited := limit > 0 && limit < 1440
+
+	fmt.Fprintln(a.stdout)
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DAY\tDATE\tPLAYED\tLIMIT")
+	for _, day := range weekly.Days {
+		// Only today is measured against the limit: Roblox doesn't say what
+		// the limit was on earlier days.
+		var note string
+		if day.DaysAgo == 0 {
+			note = "today"
+			if limited && day.MinutesPlayed > limit {
+				note += ", over by " + formatMinutes(day.MinutesPlayed-limit)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s", day.Date.Format("Mon"), day.Date.Format("Jan 2"), formatMinutes(day.MinutesPlayed), formatDuration(limit))
+		if note != "" {
+			fmt.Fprintf(tw, "\t%s", note)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(a.stdout)
+	total := weekly.Total()
+	if total >= 60 {
+		fmt.Fprintf(a.stdout, "Total: %s (%d minutes)\n", formatMinutes(total), total)
+	} else {
+		fmt.Fprintf(a.stdout, "Total: %s\n", formatMinutes(total))
+	}
+	fmt.Fprintf(a.stdout, "Daily average: %s\n", formatMinutes(weekly.Average()))
+	return 0
+}